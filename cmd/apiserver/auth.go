@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aburluka/k8test/internal/auth"
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// browsers can't set headers of WebSocket handshake, so the token could be sent as a query parameter
+	tokenQueryParam = "access_token"
+
+	// policy file is checked for modifications with this interval
+	policyReloadInterval = 5 * time.Second
+
+	anonymous = "anonymous"
+)
+
+type (
+	principalKey struct{}
+)
+
+var (
+	errUnauthenticated = newAPIError(bucket.WsErrorCode_WS_ERROR_UNAUTHENTICATED, errors.New("valid API token is required"))
+	errForbidden       = newAPIError(bucket.WsErrorCode_WS_ERROR_FORBIDDEN, errors.New("action is denied by policy"))
+)
+
+// requestToken returns bearer token of Authorization header or access_token query parameter.
+func requestToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); len(header) > 0 {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+
+		return strings.TrimSpace(token)
+	}
+
+	return r.URL.Query().Get(tokenQueryParam)
+}
+
+// authenticate rejects requests without a valid token and keeps the principal of the token in the request context.
+// Requests are not authenticated, when no token file is configured.
+func (s *ApiServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.tokens == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, ok := s.tokens.Authenticate(requestToken(r))
+		if !ok {
+			log.WithField("remote_addr", r.RemoteAddr).Warnf("unauthenticated %s %s request", r.Method, r.URL.Path)
+
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api-server"`)
+			writeError(w, errUnauthenticated)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
+
+// requestPrincipal returns the principal of authenticated request, it is empty without authentication.
+func requestPrincipal(r *http.Request) string {
+	principal, _ := r.Context().Value(principalKey{}).(string)
+
+	return principal
+}
+
+// authorize checks the action of the request against the policy before the handler is called, every denied
+// request is audited. Requests are not authorized, when no policy file is configured.
+func (s *ApiServer) authorize(action string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.policy == nil {
+			handler(w, r)
+			return
+		}
+
+		req := auth.Request{
+			Principal: requestPrincipal(r),
+			Action:    action,
+			Namespace: requestNamespace(r),
+			Filename:  mux.Vars(r)["filename"],
+		}
+
+		switch action {
+		case auth.ActionAdmin:
+			// namespace endpoints are administered per namespace, the rest are cluster-wide
+			req.Namespace = mux.Vars(r)["namespace"]
+		case auth.ActionList:
+			req.Filename = r.URL.Query().Get("prefix")
+		}
+
+		decision := s.policy.Authorize(req)
+		if decision.Allowed {
+			handler(w, r)
+			return
+		}
+
+		principal := req.Principal
+		if len(principal) == 0 {
+			principal = anonymous
+		}
+
+		s.audit.WithFields(logrus.Fields{
+			"principal":   principal,
+			"action":      req.Action,
+			"namespace":   req.Namespace,
+			"filename":    req.Filename,
+			"rule":        decision.Rule,
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"remote_addr": r.RemoteAddr,
+		}).Warn("request is denied")
+
+		writeError(w, errForbidden)
+	}
+}
+
+// listFilter hides listed files, which the principal may not list, since a rule could deny names under
+// the prefix of the listing.
+func (s *ApiServer) listFilter(r *http.Request, namespace string, match func(*fragment.FileMeta) bool) func(*fragment.FileMeta) bool {
+	if s.policy == nil {
+		return match
+	}
+
+	principal := requestPrincipal(r)
+
+	return func(meta *fragment.FileMeta) bool {
+		if match != nil && !match(meta) {
+			return false
+		}
+
+		return s.policy.Authorize(auth.Request{
+			Principal: principal,
+			Action:    auth.ActionList,
+			Namespace: namespace,
+			Filename:  meta.Name,
+		}).Allowed
+	}
+}
+
+// watchPolicy reloads the policy file, when it is modified, invalid file is reported and the policy is kept.
+func (s *ApiServer) watchPolicy() {
+	for range time.Tick(policyReloadInterval) {
+		reloaded, err := s.policy.Reload()
+		if err != nil {
+			log.WithError(err).Error("failed to reload policy file, previous rules are kept")
+			continue
+		}
+
+		if reloaded {
+			log.Info("policy file is reloaded")
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/aburluka/k8test/internal/auth"
+	bucket "github.com/aburluka/k8test/internal/proto"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRequestToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   string
+	}{
+		{name: "bearer header", target: "/files/a", header: "Bearer 0123456789abcdef", want: "0123456789abcdef"},
+		{name: "scheme in lower case", target: "/files/a", header: "bearer 0123456789abcdef", want: "0123456789abcdef"},
+		{name: "spaces around token", target: "/files/a", header: "Bearer  0123456789abcdef ", want: "0123456789abcdef"},
+		{name: "another scheme", target: "/files/a", header: "Basic 0123456789abcdef", want: ""},
+		{name: "header without scheme", target: "/files/a", header: "0123456789abcdef", want: ""},
+		{name: "query parameter", target: "/files/a?access_token=0123456789abcdef", want: "0123456789abcdef"},
+		{name: "header takes precedence", target: "/files/a?access_token=fedcba9876543210", header: "Bearer 0123456789abcdef", want: "0123456789abcdef"},
+		{name: "no token", target: "/files/a", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if len(tt.header) > 0 {
+				r.Header.Set("Authorization", tt.header)
+			}
+
+			if got := requestToken(r); got != tt.want {
+				t.Errorf("requestToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	path := writeFile(t, "tokens.json", `{"tokens": {"alice": "0123456789abcdef"}}`)
+
+	tokens, err := auth.LoadTokens(path)
+	if err != nil {
+		t.Fatalf("LoadTokens() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		tokens        *auth.Tokens
+		header        string
+		wantStatus    int
+		wantPrincipal string
+	}{
+		{name: "valid token", tokens: tokens, header: "Bearer 0123456789abcdef", wantStatus: http.StatusOK, wantPrincipal: "alice"},
+		{name: "invalid token", tokens: tokens, header: "Bearer fedcba9876543210", wantStatus: http.StatusUnauthorized},
+		{name: "no token", tokens: tokens, wantStatus: http.StatusUnauthorized},
+		{name: "authentication is disabled", tokens: nil, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ApiServer{tokens: tt.tokens}
+
+			var principal string
+			handler := s.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, _ = r.Context().Value(principalKey{}).(string)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/files/a", nil)
+			if len(tt.header) > 0 {
+				r.Header.Set("Authorization", tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if principal != tt.wantPrincipal {
+				t.Errorf("principal = %q, want %q", principal, tt.wantPrincipal)
+			}
+
+			if tt.wantStatus == http.StatusUnauthorized && len(w.Header().Get("WWW-Authenticate")) == 0 {
+				t.Error("WWW-Authenticate header is not set")
+			}
+		})
+	}
+}
+
+func TestAuthorizeRoutes(t *testing.T) {
+	s, _ := newTestServer(t, 1)
+	for _, filename := range []string{"public-a", "public-b", "secret-a"} {
+		putTestObject(t, s, filename, testData(100))
+	}
+
+	var err error
+	s.tokens, err = auth.LoadTokens(writeFile(t, "tokens.json", `{"tokens": {"alice": "0123456789abcdef", "bob": "fedcba9876543210"}}`))
+	if err != nil {
+		t.Fatalf("LoadTokens() error = %v", err)
+	}
+
+	s.policy, err = auth.LoadPolicy(writeFile(t, "policy.json", `{"rules": [
+		{"principals": ["alice"], "actions": ["download", "list"], "effect": "allow"},
+		{"principals": ["alice"], "prefix": "secret", "effect": "deny"},
+		{"principals": ["bob"], "effect": "allow"}
+	]}`))
+	if err != nil {
+		t.Fatalf("LoadPolicy() error = %v", err)
+	}
+
+	s.audit = logrus.New()
+	s.audit.SetOutput(io.Discard)
+
+	tests := []struct {
+		name       string
+		token      string
+		method     string
+		target     string
+		wantStatus int
+		wantFiles  []string
+	}{
+		{name: "stat allowed file", token: "0123456789abcdef", method: http.MethodGet, target: "/files/public-a", wantStatus: http.StatusOK},
+		{name: "stat denied file", token: "0123456789abcdef", method: http.MethodGet, target: "/files/secret-a", wantStatus: http.StatusForbidden},
+		{name: "stat denied file with allowed prefix", token: "0123456789abcdef", method: http.MethodGet, target: "/files/secret-a?prefix=public", wantStatus: http.StatusForbidden},
+		{name: "stat in namespace", token: "0123456789abcdef", method: http.MethodGet, target: "/ns/default/files/secret-a", wantStatus: http.StatusForbidden},
+		{name: "download denied file", token: "0123456789abcdef", method: http.MethodGet, target: "/objects/secret-a", wantStatus: http.StatusForbidden},
+		{name: "upload is not allowed", token: "0123456789abcdef", method: http.MethodPut, target: "/objects/public-c", wantStatus: http.StatusForbidden},
+		{name: "list denied prefix", token: "0123456789abcdef", method: http.MethodGet, target: "/files?prefix=secret", wantStatus: http.StatusForbidden},
+		{name: "list hides denied files", token: "0123456789abcdef", method: http.MethodGet, target: "/files", wantStatus: http.StatusOK, wantFiles: []string{"public-a", "public-b"}},
+		{name: "list broad prefix hides denied files", token: "0123456789abcdef", method: http.MethodGet, target: "/ns/default/files?prefix=s", wantStatus: http.StatusOK, wantFiles: []string{}},
+		{name: "list all files", token: "fedcba9876543210", method: http.MethodGet, target: "/files", wantStatus: http.StatusOK, wantFiles: []string{"public-a", "public-b", "secret-a"}},
+		{name: "stat by another principal", token: "fedcba9876543210", method: http.MethodGet, target: "/files/secret-a", wantStatus: http.StatusOK},
+		{name: "admin action", token: "0123456789abcdef", method: http.MethodGet, target: "/buckets", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+
+			w := serve(s, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("%s %s status = %d, want %d", tt.method, tt.target, w.Code, tt.wantStatus)
+			}
+
+			if tt.wantFiles == nil {
+				return
+			}
+
+			var list bucket.FileList
+			err := json.NewDecoder(w.Body).Decode(&list)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			files := []string{}
+			for _, f := range list.Files {
+				files = append(files, f.Filename)
+			}
+
+			if !slices.Equal(files, tt.wantFiles) {
+				t.Errorf("listed files = %v, want %v", files, tt.wantFiles)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	bucket "github.com/aburluka/k8test/internal/proto"
+	"github.com/aburluka/k8test/internal/wsproto"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// framed protocol flow control: client sends up to window chunks ahead of acknowledgement,
+	// server acknowledges every ackInterval chunks and every stored fragment
+	framedWindow      = 64
+	framedAckInterval = 16
+)
+
+type (
+	// chunkReader is a source of uploaded file data, it returns io.EOF when the data is over.
+	chunkReader interface {
+		ReadChunk() ([]byte, error)
+	}
+
+	// commitNotifier is implemented by sources, which report stored bytes to the client.
+	commitNotifier interface {
+		Committed(offset int64) error
+	}
+
+	// chunkWriter is a destination of downloaded file data.
+	chunkWriter interface {
+		WriteChunk([]byte) error
+	}
+
+	// wsChunkReader reads binary messages, an empty message terminates the data.
+	wsChunkReader struct {
+		conn *websocket.Conn
+	}
+
+	// wsFramedChunkReader reads sequence numbered binary messages and acknowledges them.
+	wsFramedChunkReader struct {
+		conn      *websocket.Conn
+		seq       uint64
+		committed int64
+	}
+
+	wsChunkWriter struct {
+		conn *websocket.Conn
+	}
+
+	streamChunkReader struct {
+		r   io.Reader
+		buf []byte
+	}
+
+	streamChunkWriter struct {
+		w io.Writer
+	}
+)
+
+func (cr *wsChunkReader) ReadChunk() ([]byte, error) {
+	_, b, err := cr.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) == 0 {
+		return nil, io.EOF
+	}
+
+	return b, nil
+}
+
+func newWsFramedChunkReader(conn *websocket.Conn, committed int64) *wsFramedChunkReader {
+	return &wsFramedChunkReader{
+		conn:      conn,
+		committed: committed,
+	}
+}
+
+// ReadChunk acknowledges previous chunks periodically, since they are already passed to bucket servers.
+func (cr *wsFramedChunkReader) ReadChunk() ([]byte, error) {
+	if cr.seq > 0 && cr.seq%framedAckInterval == 0 {
+		err := cr.ack()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, b, err := cr.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	seq, data, err := wsproto.DecodeFrame(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if seq != cr.seq {
+		return nil, fmt.Errorf("unexpected chunk %d, %d expected", seq, cr.seq)
+	}
+	cr.seq++
+
+	if len(data) == 0 {
+		return nil, io.EOF
+	}
+
+	return data, nil
+}
+
+func (cr *wsFramedChunkReader) Committed(offset int64) error {
+	cr.committed = offset
+
+	return cr.ack()
+}
+
+func (cr *wsFramedChunkReader) ack() error {
+	return cr.conn.WriteJSON(&bucket.WsServerMessage{
+		Ack: &bucket.WsAck{
+			NextSeq:   cr.seq,
+			Committed: cr.committed,
+		},
+	})
+}
+
+func (cw *wsChunkWriter) WriteChunk(b []byte) error {
+	return cw.conn.WriteMessage(websocket.BinaryMessage, b)
+}
+
+func newStreamChunkReader(r io.Reader) *streamChunkReader {
+	return &streamChunkReader{
+		r:   r,
+		buf: make([]byte, readChunkSize),
+	}
+}
+
+func (cr *streamChunkReader) ReadChunk() ([]byte, error) {
+	n, err := io.ReadFull(cr.r, cr.buf)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+
+	return cr.buf[:n], nil
+}
+
+func (cw *streamChunkWriter) WriteChunk(b []byte) error {
+	_, err := cw.w.Write(b)
+	return err
+}
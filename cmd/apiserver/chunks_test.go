@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+	"github.com/aburluka/k8test/internal/wsproto"
+
+	"github.com/gorilla/websocket"
+)
+
+const testChunkSize = 1000
+
+func startFramedUpload(t *testing.T, s *ApiServer, filename string, size int64) (*websocket.Conn, *bucket.WsUploadSession) {
+	t.Helper()
+
+	conn := dialUpload(t, s, filename, &bucket.WsFileInfo{
+		Size:    size,
+		Version: bucket.WsProtocolVersion_WS_PROTOCOL_FRAMED,
+	})
+
+	var session bucket.WsUploadSession
+	err := conn.ReadJSON(&session)
+	if err != nil {
+		t.Fatalf("failed to read upload session: %v", err)
+	}
+
+	return conn, &session
+}
+
+func readServerMessage(t *testing.T, conn *websocket.Conn) *bucket.WsServerMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var msg bucket.WsServerMessage
+	err := conn.ReadJSON(&msg)
+	if err != nil {
+		t.Fatalf("failed to read server message: %v", err)
+	}
+
+	return &msg
+}
+
+func sendFrame(t *testing.T, conn *websocket.Conn, frame []byte) {
+	t.Helper()
+
+	err := conn.WriteMessage(websocket.BinaryMessage, frame)
+	if err != nil {
+		t.Fatalf("failed to send frame: %v", err)
+	}
+}
+
+func TestFramedUpload(t *testing.T) {
+	s, _ := newTestServer(t, 3)
+
+	data := testData(100 * testChunkSize)
+	conn, session := startFramedUpload(t, s, "a", int64(len(data)))
+
+	if session.GetVersion() != bucket.WsProtocolVersion_WS_PROTOCOL_FRAMED || session.GetWindow() != framedWindow || session.GetOffset() != 0 {
+		t.Fatalf("upload session = %v, want framed session with %d window from 0 offset", session, framedWindow)
+	}
+
+	messages := make(chan *bucket.WsServerMessage)
+	go func() {
+		defer close(messages)
+
+		for {
+			var msg bucket.WsServerMessage
+			err := conn.ReadJSON(&msg)
+			if err != nil {
+				return
+			}
+
+			messages <- &msg
+		}
+	}()
+
+	var (
+		acks    []*bucket.WsAck
+		nextSeq uint64
+		status  *bucket.WsUploadStatus
+	)
+	receive := func() {
+		msg, ok := <-messages
+		if !ok {
+			t.Fatal("connection is closed before upload status")
+		}
+
+		if msg.GetAck() != nil {
+			acks = append(acks, msg.GetAck())
+			nextSeq = msg.GetAck().GetNextSeq()
+		}
+
+		if msg.GetStatus() != nil {
+			status = msg.GetStatus()
+		}
+	}
+
+	// the client keeps at most window chunks ahead of the last acknowledgement
+	chunks := uint64(len(data) / testChunkSize)
+	for seq := uint64(0); seq <= chunks; seq++ {
+		for seq >= nextSeq+uint64(session.GetWindow()) {
+			receive()
+		}
+
+		chunk := data[min(seq*testChunkSize, uint64(len(data))):min((seq+1)*testChunkSize, uint64(len(data)))]
+		sendFrame(t, conn, wsproto.EncodeFrame(seq, chunk))
+	}
+
+	for status == nil {
+		receive()
+	}
+
+	sum := sha256.Sum256(data)
+	if status.GetStatus() != fragment.StatusName(fragment.UploadStatusComplete) || status.GetSize() != int64(len(data)) || status.GetChecksum() != hex.EncodeToString(sum[:]) {
+		t.Errorf("upload status = %v, want complete upload of %d bytes", status, len(data))
+	}
+
+	for i := 1; i < len(acks); i++ {
+		if acks[i].GetNextSeq() < acks[i-1].GetNextSeq() || acks[i].GetCommitted() < acks[i-1].GetCommitted() {
+			t.Errorf("acknowledgement %v goes back from %v", acks[i], acks[i-1])
+		}
+	}
+
+	if len(acks) == 0 {
+		t.Fatal("no acknowledgements are received")
+	}
+
+	if acks[len(acks)-1].GetCommitted() != int64(len(data)) {
+		t.Errorf("last acknowledgement %v doesn't commit all %d bytes", acks[len(acks)-1], len(data))
+	}
+
+	w := serve(s, httptest.NewRequest(http.MethodGet, "/objects/a", nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), data) {
+		t.Errorf("downloaded %d bytes with %d status, which differ from %d uploaded bytes", w.Body.Len(), w.Code, len(data))
+	}
+}
+
+func TestFramedUploadWindow(t *testing.T) {
+	s, _ := newTestServer(t, 3)
+
+	// fragments are larger than the window, so nothing is committed while it is sent
+	size := int64(serverNumber * 2 * framedWindow * testChunkSize)
+	conn, session := startFramedUpload(t, s, "a", size)
+
+	for seq := range uint64(session.GetWindow()) {
+		sendFrame(t, conn, wsproto.EncodeFrame(seq, testData(testChunkSize)))
+	}
+
+	for seq := uint64(framedAckInterval); seq <= framedWindow; seq += framedAckInterval {
+		ack := readServerMessage(t, conn).GetAck()
+		if ack.GetNextSeq() != seq || ack.GetCommitted() != 0 {
+			t.Errorf("acknowledgement = %v, want %d next sequence number without committed bytes", ack, seq)
+		}
+	}
+}
+
+func TestFramedUploadInvalidFrames(t *testing.T) {
+	tests := []struct {
+		name    string
+		frames  [][]byte
+		wantErr string
+	}{
+		{
+			name:    "skipped chunk",
+			frames:  [][]byte{wsproto.EncodeFrame(0, []byte("a")), wsproto.EncodeFrame(2, []byte("b"))},
+			wantErr: "unexpected chunk 2, 1 expected",
+		},
+		{
+			name:    "repeated chunk",
+			frames:  [][]byte{wsproto.EncodeFrame(0, []byte("a")), wsproto.EncodeFrame(0, []byte("a"))},
+			wantErr: "unexpected chunk 0, 1 expected",
+		},
+		{
+			name:    "frame without header",
+			frames:  [][]byte{{0, 1, 2}},
+			wantErr: wsproto.ErrInvalidFrame.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t, 3)
+
+			conn, _ := startFramedUpload(t, s, "a", 100)
+			for _, frame := range tt.frames {
+				sendFrame(t, conn, frame)
+			}
+
+			var status *bucket.WsUploadStatus
+			for status == nil {
+				status = readServerMessage(t, conn).GetStatus()
+			}
+
+			if status.GetStatus() != fragment.StatusName(fragment.UploadStatusIncomplete) || !strings.Contains(status.GetError(), tt.wantErr) {
+				t.Errorf("upload status = %v, want incomplete upload with %q error", status, tt.wantErr)
+			}
+		})
+	}
+}
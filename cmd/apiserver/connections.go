@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aburluka/k8test/internal/mtls"
+	bucket "github.com/aburluka/k8test/internal/proto"
+	"github.com/aburluka/k8test/internal/registry"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	keepaliveTime    = 30 * time.Second
+	keepaliveTimeout = 10 * time.Second
+
+	// connections to addresses, which are not registered, e.g. replicas of deregistered bucket servers,
+	// are closed, when they are not used for this time
+	connectionIdleTimeout = 10 * time.Minute
+	// connections, which fail for this time, are closed, the bucket server is connected again, when it registers
+	connectionFailureTimeout = 10 * time.Minute
+)
+
+type (
+	connectionPool struct {
+		lock    sync.Mutex
+		conns   map[string]*bucketConn
+		buckets *registry.Registry
+		// TLS credentials of connections, they are not encrypted when nil
+		tls *mtls.Credentials
+	}
+
+	bucketConn struct {
+		conn   *grpc.ClientConn
+		client bucket.BucketServiceClient
+		stop   context.CancelFunc
+
+		lastUsed    time.Time
+		failedSince time.Time // zero, while the connection doesn't fail
+	}
+)
+
+func newConnectionPool(buckets *registry.Registry, tls *mtls.Credentials) *connectionPool {
+	return &connectionPool{
+		conns:   make(map[string]*bucketConn),
+		buckets: buckets,
+		tls:     tls,
+	}
+}
+
+func (p *connectionPool) client(address string) (bucket.BucketServiceClient, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if c, ok := p.conns[address]; ok {
+		c.lastUsed = time.Now()
+		return c.client, nil
+	}
+
+	transport := insecure.NewCredentials()
+	if p.tls != nil {
+		// bucket server must have a certificate for the host, which it is registered with
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		transport = p.tls.ClientCredentials(host)
+	}
+
+	conn, err := grpc.NewClient(address,
+		grpc.WithTransportCredentials(transport),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                keepaliveTime,
+			Timeout:             keepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	c := &bucketConn{
+		conn:     conn,
+		client:   bucket.NewBucketServiceClient(conn),
+		stop:     stop,
+		lastUsed: time.Now(),
+	}
+	p.conns[address] = c
+
+	go p.watch(ctx, address, c)
+
+	return c.client, nil
+}
+
+func (p *connectionPool) watch(ctx context.Context, address string, c *bucketConn) {
+	for {
+		state := c.conn.GetState()
+		switch state {
+		case connectivity.Idle:
+			// idle connection is not checked by keepalive, so failed bucket server is noticed only by requests
+			if p.buckets.Registered(address) {
+				c.conn.Connect()
+			}
+		case connectivity.Ready:
+			p.setFailed(c, false)
+			p.buckets.SetHealth(address, true, state.String())
+		case connectivity.TransientFailure:
+			p.setFailed(c, true)
+			p.buckets.SetHealth(address, false, state.String())
+		}
+
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
+}
+
+func (p *connectionPool) setFailed(c *bucketConn, failed bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	switch {
+	case !failed:
+		c.failedSince = time.Time{}
+	case c.failedSince.IsZero():
+		c.failedSince = time.Now()
+	}
+}
+
+func (p *connectionPool) closeStale(now time.Time) {
+	var stale []string
+
+	p.lock.Lock()
+	for address, c := range p.conns {
+		failed := !c.failedSince.IsZero() && now.Sub(c.failedSince) > connectionFailureTimeout
+		idle := now.Sub(c.lastUsed) > connectionIdleTimeout && !p.buckets.Registered(address)
+		if failed || idle {
+			stale = append(stale, address)
+		}
+	}
+	p.lock.Unlock()
+
+	for _, address := range stale {
+		log.WithField("address", address).Info("closing stale bucket server connection")
+
+		p.evict(address)
+		p.buckets.SetHealth(address, false, connectivity.Shutdown.String())
+	}
+}
+
+func (p *connectionPool) evict(address string) {
+	p.lock.Lock()
+	c, ok := p.conns[address]
+	delete(p.conns, address)
+	p.lock.Unlock()
+
+	if !ok {
+		return
+	}
+
+	c.stop()
+
+	err := c.conn.Close()
+	if err != nil {
+		log.WithError(err).WithField("address", address).Warn("failed to close bucket server connection")
+	}
+}
+
+func (s *ApiServer) listBuckets(w http.ResponseWriter, r *http.Request) {
+	list := &bucket.BucketList{}
+	for _, server := range s.bucketRegistry.Servers() {
+		list.Buckets = append(list.Buckets, &bucket.BucketInfo{
+			Address: server.Address,
+			Healthy: !server.Unhealthy,
+			State:   server.State,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aburluka/k8test/internal/registry"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+func waitState(t *testing.T, p *connectionPool, address string, want connectivity.State) {
+	t.Helper()
+
+	c := pooled(p, address)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for state := c.conn.GetState(); state != want; state = c.conn.GetState() {
+		if !c.conn.WaitForStateChange(ctx, state) {
+			t.Fatalf("connection to %s state = %s, want %s", address, state, want)
+		}
+	}
+}
+
+func pooled(p *connectionPool, address string) *bucketConn {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.conns[address]
+}
+
+func TestConnectionPoolCloseStale(t *testing.T) {
+	buckets := registry.NewRegistry()
+	p := newConnectionPool(buckets, nil)
+
+	registered, unregistered := startTestBucket(t), startTestBucket(t)
+	err := buckets.Register(&registry.Server{Address: registered.address})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, address := range []string{registered.address, unregistered.address} {
+		_, err = p.client(address)
+		if err != nil {
+			t.Fatalf("client(%s) error = %v", address, err)
+		}
+		t.Cleanup(func() { p.evict(address) })
+	}
+
+	// only registered bucket servers are kept connected
+	waitState(t, p, registered.address, connectivity.Ready)
+	if state := pooled(p, unregistered.address).conn.GetState(); state != connectivity.Idle {
+		t.Errorf("connection to not registered address state = %s, want %s", state, connectivity.Idle)
+	}
+
+	now := time.Now()
+	p.closeStale(now.Add(connectionIdleTimeout / 2))
+	if pooled(p, registered.address) == nil || pooled(p, unregistered.address) == nil {
+		t.Fatal("recently used connections are closed")
+	}
+
+	p.closeStale(now.Add(2 * connectionIdleTimeout))
+	if pooled(p, unregistered.address) != nil {
+		t.Error("unused connection to not registered address is kept")
+	}
+	if pooled(p, registered.address) == nil {
+		t.Fatal("unused connection to registered bucket server is closed")
+	}
+
+	// failed connection is closed after the timeout, the bucket server is unhealthy until it registers again
+	p.setFailed(pooled(p, registered.address), true)
+	p.closeStale(time.Now().Add(connectionFailureTimeout / 2))
+	if pooled(p, registered.address) == nil {
+		t.Fatal("connection is closed right after the failure")
+	}
+
+	p.closeStale(time.Now().Add(2 * connectionFailureTimeout))
+	if pooled(p, registered.address) != nil {
+		t.Fatal("failed connection is kept")
+	}
+
+	servers := buckets.Servers()
+	if len(servers) != 1 || !servers[0].Unhealthy || servers[0].State != connectivity.Shutdown.String() {
+		t.Errorf("servers = %+v, want unhealthy bucket server with closed connection", servers)
+	}
+
+	err = buckets.Register(&registry.Server{Address: registered.address})
+	if err != nil {
+		t.Fatalf("Register() of failed bucket server error = %v", err)
+	}
+
+	_, err = p.client(registered.address)
+	if err != nil {
+		t.Fatalf("client() error = %v", err)
+	}
+	waitState(t, p, registered.address, connectivity.Ready)
+}
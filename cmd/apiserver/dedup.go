@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aburluka/k8test/internal/chunker"
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	minChunkSize = 256 << 10
+	avgChunkSize = 1 << 20
+	maxChunkSize = 4 << 20
+
+	// chunk, which is not referenced anymore, can be stored again only after cleanup deletes it
+	chunkDeletingRetryInterval = time.Second
+)
+
+// storeDedupFile splits data, read from the source, to content-defined chunks. Chunks, which are already
+// stored for any file, are only referenced, the rest are stored on bucket servers chosen by the chunk hash.
+func (s *ApiServer) storeDedupFile(ctx context.Context, session *fragment.FileMeta, resumable bool, source chunkReader) (string, error) {
+	filename, uploadID, fileSize := session.Name, session.UploadID, session.Size
+
+	fileHash, err := restoreFileHash(session)
+	if err != nil {
+		s.fragmentRegistry.SetStatus(uploadID, fragment.UploadStatusFailed)
+		return "", err
+	}
+
+	var (
+		committed  = session.Committed()
+		totalBytes = committed
+		stored     int
+		referenced int
+	)
+
+	complete := false
+	defer func() {
+		if !complete {
+			s.detachUpload(uploadID, resumable)
+		}
+	}()
+
+	// namespace could be replicated before the server was started with deduplication
+	if session.Replication > 1 {
+		return "", errDedupReplication
+	}
+
+	if fileSize > committed && s.bucketRegistry.Count() == 0 {
+		return "", errNoCapacity
+	}
+
+	log.Infof("uploading file %s (%s) with %d size from %d offset, splitting to chunks", filename, uploadID, fileSize, committed)
+
+	c, err := chunker.New(minChunkSize, avgChunkSize, maxChunkSize, func(data []byte) error {
+		// file checksum state is saved with every chunk, so it must include only stored chunks
+		fileHash.Write(data)
+		hashState, err := fileHash.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("failed to save file checksum state: %w", err)
+		}
+
+		sum := sha256.Sum256(data)
+		found, err := s.storeChunk(ctx, uploadID, hex.EncodeToString(sum[:]), session.Codec, data, hashState)
+		if err != nil {
+			return err
+		}
+
+		if found {
+			referenced++
+		} else {
+			stored++
+		}
+
+		committed += int64(len(data))
+		if notifier, ok := source.(commitNotifier); ok {
+			return notifier.Committed(committed)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		b, err := source.ReadChunk()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("failed to read chunk: %w", err)
+		}
+
+		if totalBytes+int64(len(b)) > fileSize {
+			return "", newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, fmt.Errorf("received more than %d bytes", fileSize))
+		}
+		totalBytes += int64(len(b))
+
+		err = c.Write(b)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if totalBytes != fileSize {
+		return "", fmt.Errorf("received %d bytes, %d expected", totalBytes, fileSize)
+	}
+
+	err = c.Flush()
+	if err != nil {
+		return "", err
+	}
+
+	checksum, err := s.completeUpload(session, fileHash)
+	if err != nil {
+		return "", err
+	}
+
+	complete = true
+
+	log.WithFields(logrus.Fields{"filename": filename, "stored": stored, "referenced": referenced}).Info("deduplicated file is stored")
+
+	return checksum, nil
+}
+
+// storeChunk adds the chunk to the upload manifest, storing it first, when it is not stored yet.
+// It reports whether the chunk was already stored, such chunk is kept compressed with its own codec.
+func (s *ApiServer) storeChunk(ctx context.Context, uploadID, hash, codec string, data []byte, hashState []byte) (bool, error) {
+	for {
+		found, err := s.fragmentRegistry.RefChunk(uploadID, hash, hashState)
+		if errors.Is(err, fragment.ErrChunkDeleting) {
+			err = waitContext(ctx, chunkDeletingRetryInterval)
+			if err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		if err != nil {
+			return false, fmt.Errorf("failed to update registry record: %w", err)
+		}
+
+		if found {
+			return true, nil
+		}
+
+		chunk, dataKey, err := s.newChunk(hash, codec, int64(len(data)))
+		if err != nil {
+			return false, err
+		}
+
+		// chunk is stored with its hash as a name, so the stored one is the same
+		err = s.putFragment(ctx, chunk.Key, 0, chunk.Address, codec, dataKey, data)
+		if err != nil && status.Code(err) != codes.AlreadyExists {
+			return false, fmt.Errorf("failed to store chunk on %s: %w", chunk.Address, err)
+		}
+
+		recorded, err := s.addChunk(uploadID, hash, chunk, hashState)
+		if errors.Is(err, fragment.ErrChunkDeleting) {
+			err = waitContext(ctx, chunkDeletingRetryInterval)
+			if err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		if err != nil {
+			return false, fmt.Errorf("failed to update registry record: %w", err)
+		}
+
+		// the same chunk was stored concurrently, the copy is not needed
+		if recorded.Key != chunk.Key || recorded.Address != chunk.Address {
+			err = s.deleteFragment(chunk.Key, chunk.Address, 0)
+			if err != nil {
+				log.WithError(err).WithFields(logrus.Fields{"hash": hash, "address": chunk.Address}).Warn("failed to delete duplicate chunk")
+			}
+		}
+
+		return false, nil
+	}
+}
+
+// newChunk chooses bucket server for the chunk by its hash and generates its data key, when encryption
+// at rest is enabled. Encrypted chunk is stored under unique name, so a copy left by interrupted upload
+// is never taken for the chunk encrypted with another key.
+func (s *ApiServer) newChunk(hash, codec string, size int64) (fragment.Chunk, []byte, error) {
+	chunk := fragment.Chunk{
+		Key:   fragment.ChunkKey(hash, codec),
+		Size:  size,
+		Codec: codec,
+	}
+
+	bucketServer := s.chooseServer(chunk.Key, 0, nil)
+	if bucketServer == nil {
+		return chunk, nil, errNoCapacity
+	}
+	chunk.Address = bucketServer.Address
+
+	if s.keyring == nil {
+		return chunk, nil, nil
+	}
+
+	dataKey, wrapped, err := s.keyring.NewDataKey()
+	if err != nil {
+		return chunk, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	chunk.DataKey = wrapped
+
+	suffix := make([]byte, 8)
+	_, err = rand.Read(suffix)
+	if err != nil {
+		return chunk, nil, err
+	}
+	chunk.Key += "." + hex.EncodeToString(suffix)
+
+	return chunk, dataKey, nil
+}
+
+func waitContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// application close codes are 4000 + error code
+	wsCloseCodeBase = 4000
+)
+
+type (
+	// apiError is an error with a machine-readable code, reported to clients.
+	apiError struct {
+		code bucket.WsErrorCode
+		err  error
+	}
+)
+
+var (
+	errNoCapacity       = newAPIError(bucket.WsErrorCode_WS_ERROR_NO_CAPACITY, errors.New("no bucket servers are registered"))
+	errChecksumMismatch = newAPIError(bucket.WsErrorCode_WS_ERROR_CHECKSUM_MISMATCH, errors.New("checksum mismatch"))
+
+	// deduplicated chunks are stored once per cluster, so they can't have replicas
+	errDedupReplication = newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, errors.New("replication is not supported with deduplication"))
+
+	// bucket server persisted other data, than it was sent
+	errStoredMismatch = errors.New("stored fragment doesn't match sent data")
+
+	httpStatuses = map[bucket.WsErrorCode]int{
+		bucket.WsErrorCode_WS_ERROR_INTERNAL:            http.StatusInternalServerError,
+		bucket.WsErrorCode_WS_ERROR_BAD_REQUEST:         http.StatusBadRequest,
+		bucket.WsErrorCode_WS_ERROR_NOT_FOUND:           http.StatusNotFound,
+		bucket.WsErrorCode_WS_ERROR_NO_CAPACITY:         http.StatusServiceUnavailable,
+		bucket.WsErrorCode_WS_ERROR_FILE_EXISTS:         http.StatusPreconditionFailed,
+		bucket.WsErrorCode_WS_ERROR_CHECKSUM_MISMATCH:   http.StatusUnprocessableEntity,
+		bucket.WsErrorCode_WS_ERROR_QUOTA_EXCEEDED:      http.StatusInsufficientStorage,
+		bucket.WsErrorCode_WS_ERROR_UPLOAD_ACTIVE:       http.StatusConflict,
+		bucket.WsErrorCode_WS_ERROR_NAMESPACE_NOT_EMPTY: http.StatusConflict,
+		bucket.WsErrorCode_WS_ERROR_UNAUTHENTICATED:     http.StatusUnauthorized,
+		bucket.WsErrorCode_WS_ERROR_FORBIDDEN:           http.StatusForbidden,
+	}
+)
+
+func newAPIError(code bucket.WsErrorCode, err error) *apiError {
+	return &apiError{
+		code: code,
+		err:  err,
+	}
+}
+
+func (e *apiError) Error() string {
+	return e.err.Error()
+}
+
+func (e *apiError) Unwrap() error {
+	return e.err
+}
+
+// errorCode classifies the error, registry errors are mapped to their codes.
+func errorCode(err error) bucket.WsErrorCode {
+	var apiErr *apiError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.code
+	case errors.Is(err, fragment.ErrFileExists):
+		return bucket.WsErrorCode_WS_ERROR_FILE_EXISTS
+	case errors.Is(err, fragment.ErrUploadActive):
+		return bucket.WsErrorCode_WS_ERROR_UPLOAD_ACTIVE
+	case errors.Is(err, fragment.ErrNotFound), errors.Is(err, fragment.ErrNamespaceNotFound):
+		return bucket.WsErrorCode_WS_ERROR_NOT_FOUND
+	case errors.Is(err, fragment.ErrQuotaExceeded):
+		return bucket.WsErrorCode_WS_ERROR_QUOTA_EXCEEDED
+	case errors.Is(err, fragment.ErrNamespaceNotEmpty):
+		return bucket.WsErrorCode_WS_ERROR_NAMESPACE_NOT_EMPTY
+	case errors.Is(err, fragment.ErrInvalidNamespace), errors.Is(err, fragment.ErrDefaultNamespace):
+		return bucket.WsErrorCode_WS_ERROR_BAD_REQUEST
+	}
+
+	return bucket.WsErrorCode_WS_ERROR_INTERNAL
+}
+
+func errorReason(code bucket.WsErrorCode) string {
+	return strings.ToLower(strings.TrimPrefix(code.String(), "WS_ERROR_"))
+}
+
+func wsError(err error) *bucket.WsError {
+	code := errorCode(err)
+
+	return &bucket.WsError{
+		Code:    code,
+		Reason:  errorReason(code),
+		Message: err.Error(),
+	}
+}
+
+// closeWithError closes WebSocket connection with the application close code of the error.
+func closeWithError(conn *websocket.Conn, err error) {
+	code := errorCode(err)
+
+	msg := websocket.FormatCloseMessage(wsCloseCodeBase+int(code), errorReason(code))
+	conn.WriteMessage(websocket.CloseMessage, msg)
+}
+
+// writeError responds with HTTP status of the error and error details as JSON body.
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, httpStatuses[errorCode(err)], wsError(err))
+}
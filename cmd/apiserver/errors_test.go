@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+	"github.com/aburluka/k8test/internal/wsproto"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bucket.WsErrorCode
+	}{
+		{name: "api error", err: errNoCapacity, want: bucket.WsErrorCode_WS_ERROR_NO_CAPACITY},
+		{name: "wrapped api error", err: fmt.Errorf("failed to upload: %w", errChecksumMismatch), want: bucket.WsErrorCode_WS_ERROR_CHECKSUM_MISMATCH},
+		{name: "file exists", err: fmt.Errorf("failed to stage upload: %w", fragment.ErrFileExists), want: bucket.WsErrorCode_WS_ERROR_FILE_EXISTS},
+		{name: "upload is active", err: fragment.ErrUploadActive, want: bucket.WsErrorCode_WS_ERROR_UPLOAD_ACTIVE},
+		{name: "not found", err: fragment.ErrNotFound, want: bucket.WsErrorCode_WS_ERROR_NOT_FOUND},
+		{name: "unknown error", err: errors.New("failed"), want: bucket.WsErrorCode_WS_ERROR_INTERNAL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCode(tt.err); got != tt.want {
+				t.Errorf("errorCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// readCloseError reads server messages until the connection is closed.
+func readCloseError(t *testing.T, conn *websocket.Conn) (*websocket.CloseError, *bucket.WsError) {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var wsErr *bucket.WsError
+	for {
+		_, b, err := conn.ReadMessage()
+
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			return closeErr, wsErr
+		}
+
+		if err != nil {
+			t.Fatalf("connection is closed without close message: %v", err)
+		}
+
+		var msg bucket.WsServerMessage
+		err = json.Unmarshal(b, &msg)
+		if err != nil {
+			t.Fatalf("failed to decode server message: %v", err)
+		}
+
+		if msg.GetError() != nil {
+			wsErr = msg.GetError()
+		}
+	}
+}
+
+func TestUploadCloseCode(t *testing.T) {
+	data := testData(100)
+	wrongChecksum := fmt.Sprintf("%064x", 0)
+
+	tests := []struct {
+		name       string
+		buckets    int
+		existing   bool
+		fileInfo   *bucket.WsFileInfo
+		frames     [][]byte
+		wantCode   bucket.WsErrorCode
+		wantFramed bool
+	}{
+		{
+			name:     "negative size",
+			buckets:  1,
+			fileInfo: &bucket.WsFileInfo{Size: -1},
+			wantCode: bucket.WsErrorCode_WS_ERROR_BAD_REQUEST,
+		},
+		{
+			name:     "existing file",
+			buckets:  1,
+			existing: true,
+			fileInfo: &bucket.WsFileInfo{Size: int64(len(data)), Exclusive: true},
+			wantCode: bucket.WsErrorCode_WS_ERROR_FILE_EXISTS,
+		},
+		{
+			name:     "no buckets",
+			fileInfo: &bucket.WsFileInfo{Size: int64(len(data))},
+			frames:   [][]byte{data, {}},
+			wantCode: bucket.WsErrorCode_WS_ERROR_NO_CAPACITY,
+		},
+		{
+			name:       "no buckets for framed upload",
+			fileInfo:   &bucket.WsFileInfo{Size: int64(len(data)), Version: bucket.WsProtocolVersion_WS_PROTOCOL_FRAMED},
+			frames:     [][]byte{wsproto.EncodeFrame(0, data), wsproto.EncodeFrame(1, nil)},
+			wantCode:   bucket.WsErrorCode_WS_ERROR_NO_CAPACITY,
+			wantFramed: true,
+		},
+		{
+			name:       "checksum mismatch",
+			buckets:    1,
+			fileInfo:   &bucket.WsFileInfo{Size: int64(len(data)), Version: bucket.WsProtocolVersion_WS_PROTOCOL_FRAMED, Checksum: wrongChecksum},
+			frames:     [][]byte{wsproto.EncodeFrame(0, data), wsproto.EncodeFrame(1, nil)},
+			wantCode:   bucket.WsErrorCode_WS_ERROR_CHECKSUM_MISMATCH,
+			wantFramed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t, tt.buckets)
+			if tt.existing {
+				putTestObject(t, s, "a", data)
+			}
+
+			conn := dialUpload(t, s, "a", tt.fileInfo)
+			if tt.wantFramed {
+				var session bucket.WsUploadSession
+				err := conn.ReadJSON(&session)
+				if err != nil {
+					t.Fatalf("failed to read upload session: %v", err)
+				}
+			}
+
+			// the server may close the connection before the data is sent
+			for _, frame := range tt.frames {
+				conn.WriteMessage(websocket.BinaryMessage, frame)
+			}
+
+			closeErr, wsErr := readCloseError(t, conn)
+			if closeErr.Code != wsCloseCodeBase+int(tt.wantCode) || closeErr.Text != errorReason(tt.wantCode) {
+				t.Errorf("close code = %d %q, want %d %q", closeErr.Code, closeErr.Text, wsCloseCodeBase+int(tt.wantCode), errorReason(tt.wantCode))
+			}
+
+			if tt.wantFramed && wsErr.GetCode() != tt.wantCode {
+				t.Errorf("error frame = %v, want %v code", wsErr, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestPutObjectError(t *testing.T) {
+	data := testData(100)
+
+	tests := []struct {
+		name       string
+		buckets    int
+		existing   bool
+		header     http.Header
+		wantStatus int
+		wantCode   bucket.WsErrorCode
+	}{
+		{
+			name:       "existing file",
+			buckets:    1,
+			existing:   true,
+			header:     http.Header{"If-None-Match": {"*"}},
+			wantStatus: http.StatusPreconditionFailed,
+			wantCode:   bucket.WsErrorCode_WS_ERROR_FILE_EXISTS,
+		},
+		{
+			name:       "checksum mismatch",
+			buckets:    1,
+			header:     http.Header{checksumHeader: {fmt.Sprintf("%064x", 0)}},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   bucket.WsErrorCode_WS_ERROR_CHECKSUM_MISMATCH,
+		},
+		{
+			name:       "no buckets",
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   bucket.WsErrorCode_WS_ERROR_NO_CAPACITY,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t, tt.buckets)
+			if tt.existing {
+				putTestObject(t, s, "a", data)
+			}
+
+			r := httptest.NewRequest(http.MethodPut, "/objects/a", bytes.NewReader(data))
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+
+			w := serve(s, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			var wsErr bucket.WsError
+			err := json.NewDecoder(w.Body).Decode(&wsErr)
+			if err != nil {
+				t.Fatalf("failed to decode error: %v", err)
+			}
+
+			if wsErr.GetCode() != tt.wantCode || wsErr.GetReason() != errorReason(tt.wantCode) {
+				t.Errorf("error = %v, want %v code", &wsErr, tt.wantCode)
+			}
+		})
+	}
+}
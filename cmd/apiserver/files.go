@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aburluka/k8test/internal/compression"
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	deleteFragmentRetries       = 3
+	deleteFragmentRetryInterval = 500 * time.Millisecond
+
+	defaultListLimit = 100
+	maxListLimit     = 1000
+
+	defaultContentType = "application/octet-stream"
+)
+
+func fileStat(meta *fragment.FileMeta) *bucket.FileStat {
+	stat := &bucket.FileStat{
+		Namespace:   meta.Namespace,
+		Filename:    meta.Name,
+		Size:        meta.Size,
+		Status:      fragment.StatusName(meta.Status),
+		Fragments:   uint32(len(meta.Fragments)),
+		Checksum:    meta.Checksum,
+		CreatedAt:   meta.CreatedAt.Format(time.RFC3339),
+		Codec:       meta.Codec,
+		Encrypted:   meta.DataKey != nil,
+		ContentType: contentType(meta),
+		Metadata:    meta.Metadata,
+	}
+
+	// files uploaded before compression was introduced are not compressed
+	if len(stat.Codec) == 0 {
+		stat.Codec = compression.None
+	}
+
+	if !meta.CompletedAt.IsZero() {
+		stat.CompletedAt = meta.CompletedAt.Format(time.RFC3339)
+	}
+
+	// files uploaded before fragment sizes were recorded have -1 sizes and offsets
+	for _, f := range meta.Fragments {
+		stat.Placement = append(stat.Placement, &bucket.FragmentInfo{
+			Fragment: uint32(f.Index),
+			Address:  f.Replicas[0],
+			Size:     f.Length,
+			Checksum: f.Checksum,
+			Offset:   f.Offset,
+			Replicas: f.Replicas,
+		})
+	}
+
+	// chunks of deduplicated file are named by their checksum and have own keys, since they are shared
+	if len(meta.Manifest) > 0 {
+		stat.Fragments = uint32(len(meta.Manifest))
+		stat.Encrypted = true
+
+		var offset int64
+		for i, ref := range meta.Manifest {
+			stat.Encrypted = stat.Encrypted && ref.DataKey != nil
+
+			stat.Placement = append(stat.Placement, &bucket.FragmentInfo{
+				Fragment: uint32(i),
+				Address:  ref.Address,
+				Size:     ref.Size,
+				Checksum: ref.Hash,
+				Offset:   offset,
+				Replicas: []string{ref.Address},
+			})
+			offset += ref.Size
+		}
+	}
+
+	return stat
+}
+
+// contentType returns media type of the file, files uploaded without it are arbitrary binary data.
+func contentType(meta *fragment.FileMeta) string {
+	if len(meta.ContentType) == 0 {
+		return defaultContentType
+	}
+
+	return meta.ContentType
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.WithError(err).Error("failed to write response")
+	}
+}
+
+func (s *ApiServer) listFiles(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := defaultListLimit
+	if l := query.Get("limit"); len(l) > 0 {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	limit = min(limit, maxListLimit)
+
+	namespace := requestNamespace(r)
+	_, err := s.fragmentRegistry.Namespace(namespace)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	files, next := s.fragmentRegistry.List(namespace, query.Get("prefix"), query.Get("cursor"), limit, s.listFilter(r, namespace, metadataFilter(query)))
+
+	list := &bucket.FileList{
+		NextCursor: next,
+	}
+	for _, meta := range files {
+		list.Files = append(list.Files, fileStat(meta))
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *ApiServer) statFile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	filename := vars["filename"]
+	if len(filename) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	meta, ok := s.fragmentRegistry.Stat(requestNamespace(r), filename)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, fileStat(meta))
+}
+
+func (s *ApiServer) deleteFragmentWithRetries(key, address string, fragment int) error {
+	var err error
+	for attempt := 0; attempt < deleteFragmentRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(deleteFragmentRetryInterval)
+		}
+
+		err = s.deleteFragment(key, address, fragment)
+		if err == nil {
+			return nil
+		}
+
+		log.WithError(err).WithFields(logrus.Fields{"key": key, "address": address, "attempt": attempt}).Warnf("failed to delete %d fragment", fragment)
+	}
+
+	return err
+}
+
+func (s *ApiServer) deleteFile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	filename := vars["filename"]
+	if len(filename) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	meta, err := s.fragmentRegistry.Tombstone(requestNamespace(r), filename)
+	if errors.Is(err, fragment.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.WithError(err).WithField("filename", filename).Error("failed to tombstone file")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	key := meta.StorageKey()
+	report := &bucket.DeleteFileReport{
+		Filename:  filename,
+		Fragments: uint32(len(meta.Fragments)),
+	}
+	if len(meta.Manifest) > 0 {
+		report.Fragments = uint32(len(meta.Manifest))
+	}
+
+	switch {
+	case s.fragmentRegistry.InUse(key):
+		// fragments of the file being downloaded are removed by the cleanup process later
+		report.Deferred = true
+	case len(meta.Manifest) > 0:
+		// chunks could be shared with other files, the cleanup process removes the ones left without references
+		report.Deferred = true
+
+		err = s.fragmentRegistry.Purge(key)
+		if err != nil {
+			log.WithError(err).WithField("filename", filename).Error("failed to purge deleted file")
+		}
+	default:
+		for _, f := range meta.Fragments {
+			deleted := true
+			for _, address := range f.Replicas {
+				err = s.deleteFragmentWithRetries(key, address, f.Index)
+				if err != nil {
+					report.Failures = append(report.Failures, &bucket.FragmentDeleteFailure{
+						Fragment: uint32(f.Index),
+						Address:  address,
+						Error:    err.Error(),
+					})
+					deleted = false
+				}
+			}
+
+			if deleted {
+				report.Deleted++
+			}
+		}
+
+		if len(report.Failures) == 0 {
+			err = s.fragmentRegistry.Purge(key)
+			if err != nil {
+				log.WithError(err).WithField("filename", filename).Error("failed to purge deleted file")
+			}
+		}
+	}
+
+	log.WithFields(logrus.Fields{"filename": filename, "deleted": report.Deleted, "failed": len(report.Failures)}).Info("file is deleted")
+
+	status := http.StatusOK
+	if report.Deferred || len(report.Failures) > 0 {
+		status = http.StatusAccepted
+	}
+
+	writeJSON(w, status, report)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/aburluka/k8test/internal/compression"
+	"github.com/aburluka/k8test/internal/encryption"
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// fragmentWriter compresses data written to it with the file codec, encrypts it with the file data key
+	// and sends it to the bucket server as chunks of the fragment.
+	fragmentWriter struct {
+		stream     bucket.BucketService_UploadChunksClient
+		key        string
+		fragment   int
+		compressor io.WriteCloser
+		encryptor  io.WriteCloser
+		buf        *bufio.Writer
+
+		// bytes sent to the bucket server, which it confirms once the fragment is persisted
+		sent     int64
+		sentHash hash.Hash
+	}
+
+	// fragmentReader reads stored fragment data from the bucket server stream.
+	fragmentReader struct {
+		stream bucket.BucketService_DownloadChunksClient
+		data   []byte
+	}
+)
+
+func newFragmentWriter(stream bucket.BucketService_UploadChunksClient, key string, fragmentNumber int, codec string, dataKey []byte) (*fragmentWriter, error) {
+	w := &fragmentWriter{
+		stream:   stream,
+		key:      key,
+		fragment: fragmentNumber,
+		sentHash: sha256.New(),
+	}
+	w.buf = bufio.NewWriterSize(writerFunc(w.send), readChunkSize)
+
+	var (
+		sink io.Writer = w.buf
+		err  error
+	)
+	if dataKey != nil {
+		w.encryptor, err = encryption.NewWriter(dataKey, fragmentNumber, w.buf)
+		if err != nil {
+			return nil, err
+		}
+
+		sink = w.encryptor
+	}
+
+	w.compressor, err = compression.NewWriter(codec, sink)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *fragmentWriter) Write(p []byte) (int, error) {
+	return w.compressor.Write(p)
+}
+
+// Close sends buffered data, the stream itself is closed by the caller to receive bucket server response.
+func (w *fragmentWriter) Close() error {
+	err := w.compressor.Close()
+	if err != nil {
+		return err
+	}
+
+	if w.encryptor != nil {
+		err = w.encryptor.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.buf.Flush()
+}
+
+func (w *fragmentWriter) send(p []byte) (int, error) {
+	err := w.stream.Send(&bucket.UploadChunk{
+		Filename: w.key,
+		Fragment: uint32(w.fragment),
+		Chunk: &bucket.Chunk{
+			Data: p,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	w.sent += int64(len(p))
+	w.sentHash.Write(p)
+
+	return len(p), nil
+}
+
+// confirmFragment checks that the bucket server persisted exactly the sent data, the mismatched fragment is removed.
+func (s *ApiServer) confirmFragment(w *fragmentWriter, address string, response *bucket.UploadResponse) error {
+	checksum := hex.EncodeToString(w.sentHash.Sum(nil))
+	if response.GetSize() == w.sent && response.GetChecksum() == checksum {
+		return nil
+	}
+
+	err := s.deleteFragment(w.key, address, w.fragment)
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{"key": w.key, "address": address}).Errorf("failed to remove mismatched %d fragment", w.fragment)
+	}
+
+	return fmt.Errorf("%w: %s stored %d bytes with %s checksum, %d bytes with %s checksum are sent",
+		errStoredMismatch, address, response.GetSize(), response.GetChecksum(), w.sent, checksum)
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func (r *fragmentReader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		chunk, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+
+		r.data = chunk.GetData()
+	}
+
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+
+	return n, nil
+}
+
+// putFragment stores the whole fragment on the bucket server.
+func (s *ApiServer) putFragment(ctx context.Context, key string, fragmentNumber int, address, codec string, dataKey []byte, data []byte) error {
+	grpcClient, err := s.bucketClient(address)
+	if err != nil {
+		return err
+	}
+
+	grpcStream, err := grpcClient.UploadChunks(ctx)
+	if err != nil {
+		return err
+	}
+
+	w, err := newFragmentWriter(grpcStream, key, fragmentNumber, codec, dataKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	if err == nil {
+		err = w.Close()
+	}
+
+	// bucket server error is returned when the stream is closed
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	response, err := grpcStream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+
+	return s.confirmFragment(w, address, response)
+}
+
+// loadRange reads the fragment range from the bucket server and writes it to the destination.
+// Compressed or encrypted fragment is read from the beginning, since offsets are in original data.
+func (s *ApiServer) loadRange(ctx context.Context, grpcClient bucket.BucketServiceClient, fr fragment.FragmentRange, destination chunkWriter) error {
+	dataKey, err := s.dataKey(fr.DataKey)
+	if err != nil {
+		return err
+	}
+
+	transformed := compression.Compressed(fr.Codec) || dataKey != nil
+
+	request := &bucket.DownloadRequest{
+		Filename: fr.Key,
+		Fragment: uint32(fr.Fragment),
+	}
+	if !transformed {
+		request.Offset = fr.Offset
+		request.Length = fr.Length
+	}
+
+	// the rest of the fragment is not needed, when the range is read
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	grpcStream, err := grpcClient.DownloadChunks(ctx, request)
+	if err != nil {
+		return fmt.Errorf("failed to start file fragment downloading: %w", err)
+	}
+
+	if !transformed {
+		for {
+			chunk, err := grpcStream.Recv()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			if err != nil {
+				return fmt.Errorf("failed to download chunk: %w", err)
+			}
+
+			err = destination.WriteChunk(chunk.GetData())
+			if err != nil {
+				return fmt.Errorf("failed to sent chunk: %w", err)
+			}
+		}
+	}
+
+	var stored io.Reader = &fragmentReader{stream: grpcStream}
+	if dataKey != nil {
+		stored, err = encryption.NewReader(dataKey, fr.Fragment, stored)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt fragment: %w", err)
+		}
+	}
+
+	r, err := compression.NewReader(fr.Codec, stored)
+	if err != nil {
+		return fmt.Errorf("failed to decompress fragment: %w", err)
+	}
+	defer r.Close()
+
+	_, err = io.CopyN(io.Discard, r, fr.Offset)
+	if err != nil {
+		return fmt.Errorf("failed to skip to %d offset of fragment: %w", fr.Offset, err)
+	}
+
+	var source io.Reader = r
+	if fr.Length > 0 {
+		source = io.LimitReader(r, fr.Length)
+	}
+
+	var (
+		loaded int64
+		buf    = make([]byte, readChunkSize)
+	)
+	for {
+		n, err := source.Read(buf)
+		if n > 0 {
+			loaded += int64(n)
+
+			err := destination.WriteChunk(buf[:n])
+			if err != nil {
+				return fmt.Errorf("failed to sent chunk: %w", err)
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to download chunk: %w", err)
+		}
+	}
+
+	if fr.Length > 0 && loaded != fr.Length {
+		return fmt.Errorf("fragment has %d bytes, %d expected", loaded, fr.Length)
+	}
+
+	return nil
+}
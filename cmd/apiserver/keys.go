@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aburluka/k8test/internal/encryption"
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+)
+
+var (
+	errNoKeyring = errors.New("encryption at rest is not configured")
+)
+
+// newDataKey returns wrapped key for a new file, files are not encrypted without master key file.
+// Deduplicated files have no own key, every chunk is encrypted with its own one.
+func (s *ApiServer) newDataKey() (*encryption.WrappedKey, error) {
+	if s.keyring == nil || s.dedup {
+		return nil, nil
+	}
+
+	_, wrapped, err := s.keyring.NewDataKey()
+
+	return wrapped, err
+}
+
+// beginUpload stages the upload with a new data key, key rotation waits until the key is registered.
+func (s *ApiServer) beginUpload(namespace, filename string, size int64, opts fragment.UploadOptions) (*fragment.FileMeta, error) {
+	s.keyLock.RLock()
+	defer s.keyLock.RUnlock()
+
+	var err error
+	opts.DataKey, err = s.newDataKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	return s.fragmentRegistry.BeginUpload(namespace, filename, size, opts)
+}
+
+// addChunk records the stored chunk. Its key is wrapped before the chunk is stored, so it is rewrapped,
+// when master key was rotated in the meantime.
+func (s *ApiServer) addChunk(uploadID, hash string, chunk fragment.Chunk, hashState []byte) (fragment.Chunk, error) {
+	s.keyLock.RLock()
+	defer s.keyLock.RUnlock()
+
+	if chunk.DataKey != nil {
+		var err error
+		chunk.DataKey, _, err = s.keyring.Rewrap(chunk.DataKey)
+		if err != nil {
+			return chunk, fmt.Errorf("failed to rewrap chunk key: %w", err)
+		}
+	}
+
+	return s.fragmentRegistry.AddChunk(uploadID, hash, chunk, hashState)
+}
+
+// dataKey unwraps key of encrypted fragments, it returns nil for fragments stored as is.
+func (s *ApiServer) dataKey(wrapped *encryption.WrappedKey) ([]byte, error) {
+	if wrapped == nil {
+		return nil, nil
+	}
+
+	if s.keyring == nil {
+		return nil, errNoKeyring
+	}
+
+	return s.keyring.Unwrap(wrapped)
+}
+
+// rotateKeys reloads master key file and rewraps data keys, which are not wrapped by the current master key.
+// Stored fragments are not changed, the old master key could be removed from the key file afterwards.
+func (s *ApiServer) rotateKeys(w http.ResponseWriter, r *http.Request) {
+	if s.keyring == nil {
+		writeError(w, newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, errNoKeyring))
+		return
+	}
+
+	// keys, which are wrapped by the old master key and not registered yet, would be missed by rewrapping
+	s.keyLock.Lock()
+	defer s.keyLock.Unlock()
+
+	err := s.keyring.Reload()
+	if err != nil {
+		log.WithError(err).Error("failed to reload master key file")
+		writeError(w, err)
+		return
+	}
+
+	rewrapped, err := s.fragmentRegistry.RewrapKeys(s.keyring.Rewrap)
+	if err != nil {
+		log.WithError(err).Error("failed to rewrap data keys")
+		writeError(w, err)
+		return
+	}
+
+	log.WithField("key_id", s.keyring.Current()).Infof("%d data keys are rewrapped", rewrapped)
+
+	writeJSON(w, http.StatusOK, &bucket.KeyRotationReport{
+		KeyId:     s.keyring.Current(),
+		Rewrapped: uint32(rewrapped),
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/aburluka/k8test/internal/encryption"
+	"github.com/aburluka/k8test/internal/fragment"
+)
+
+// testKeyFile returns content of master key file with the keys, every key is derived from its ID.
+func testKeyFile(current string, ids ...string) string {
+	keys := make(map[string]string)
+	for _, id := range ids {
+		keys[id] = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte(id), encryption.KeySize)[:encryption.KeySize])
+	}
+
+	b, _ := json.Marshal(map[string]any{"current": current, "keys": keys})
+
+	return string(b)
+}
+
+// newKeyringServer returns the server with "k1" master key and rotates it to "k2".
+func newKeyringServer(t *testing.T) (*ApiServer, func()) {
+	t.Helper()
+
+	s, _ := newTestServer(t, 1)
+
+	path := writeFile(t, "keys.json", testKeyFile("k1", "k1"))
+
+	var err error
+	s.keyring, err = encryption.LoadKeyring(path)
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+
+	rotate := func() {
+		err := os.WriteFile(path, []byte(testKeyFile("k2", "k1", "k2")), 0o600)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		w := serve(s, httptest.NewRequest(http.MethodPost, "/keys/rotate", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("POST /keys/rotate status = %d, want %d", w.Code, http.StatusOK)
+		}
+	}
+
+	return s, rotate
+}
+
+func TestRotateKeysDuringUpload(t *testing.T) {
+	s, rotate := newKeyringServer(t)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := s.beginUpload(fragment.DefaultNamespace, fmt.Sprintf("file-%d", i), 100, fragment.UploadOptions{})
+			if err != nil {
+				t.Errorf("beginUpload() error = %v", err)
+			}
+		}()
+
+		if i == 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rotate()
+			}()
+		}
+	}
+	wg.Wait()
+
+	if len(s.fragmentRegistry.Uploads) != 20 {
+		t.Fatalf("%d uploads are staged, want 20", len(s.fragmentRegistry.Uploads))
+	}
+
+	// keys of uploads, staged before the rotation, are rewrapped, the later ones are wrapped by the new key
+	for _, fm := range s.fragmentRegistry.Uploads {
+		if fm.DataKey == nil || fm.DataKey.KeyID != "k2" {
+			t.Errorf("key of %s is wrapped by %+v, want k2 master key", fm.Name, fm.DataKey)
+		}
+	}
+}
+
+func TestRotateKeysDuringChunkUpload(t *testing.T) {
+	s, rotate := newKeyringServer(t)
+	s.dedup = true
+
+	session, err := s.beginUpload(fragment.DefaultNamespace, "a", 100, fragment.UploadOptions{})
+	if err != nil {
+		t.Fatalf("beginUpload() error = %v", err)
+	}
+
+	chunk, dataKey, err := s.newChunk("hash", "none", 100)
+	if err != nil {
+		t.Fatalf("newChunk() error = %v", err)
+	}
+
+	// the master key is rotated, while the chunk is being stored
+	rotate()
+
+	recorded, err := s.addChunk(session.UploadID, "hash", chunk, nil)
+	if err != nil {
+		t.Fatalf("addChunk() error = %v", err)
+	}
+
+	if recorded.DataKey.KeyID != "k2" {
+		t.Errorf("chunk key is wrapped by %s master key, want k2", recorded.DataKey.KeyID)
+	}
+
+	unwrapped, err := s.dataKey(recorded.DataKey)
+	if err != nil || !bytes.Equal(unwrapped, dataKey) {
+		t.Errorf("unwrapped chunk key differs from the generated one, error = %v", err)
+	}
+}
@@ -2,16 +2,30 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
+	"flag"
+	"fmt"
+	"hash"
 	"hash/fnv"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
 
 	"time"
 
+	"github.com/aburluka/k8test/internal/auth"
+	"github.com/aburluka/k8test/internal/compression"
+	"github.com/aburluka/k8test/internal/encryption"
 	"github.com/aburluka/k8test/internal/fragment"
+	"github.com/aburluka/k8test/internal/mtls"
 	bucket "github.com/aburluka/k8test/internal/proto"
 	"github.com/aburluka/k8test/internal/registry"
 
@@ -20,7 +34,6 @@ import (
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health"
 	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
@@ -30,8 +43,22 @@ type (
 	ApiServer struct {
 		bucketRegistry   *registry.Registry
 		fragmentRegistry *fragment.Registry
+		fragmentPlanner  fragment.Planner
+		dedup            bool
+		codec            string
+		keyring          *encryption.Keyring
+		keyLock          sync.RWMutex // key rotation waits for data keys, which are being registered
+		tokens           *auth.Tokens
+		policy           *auth.Policy
+		audit            *logrus.Logger
+		credentials      *mtls.Credentials
+		connections      *connectionPool
 		Router           *mux.Router
 
+		uploadParallelism   int
+		downloadParallelism int
+		downloadMemory      int64
+
 		cleanupTicker *time.Ticker
 		bucket.UnimplementedApiServiceServer
 	}
@@ -41,15 +68,59 @@ const (
 	readChunkSize = 2 << 18
 	serverNumber  = 6
 
+	minFragmentSize = 1 << 20
+	// sent data of a fragment is kept until the fragment is stored to replay it to a spare bucket server,
+	// so the limit bounds memory of every fragment in flight
+	maxFragmentSize = 16 << 20
+
 	cleanupInterval = 10 * time.Second
+
+	checksumHeader = "X-File-Checksum"
+	codecHeader    = "X-File-Codec"
+	sizeHeader     = "X-File-Size"
+
+	// interrupted resumable uploads are failed, when they are not resumed in time
+	uploadSessionTTL = 24 * time.Hour
 )
 
 var (
 	log      = logrus.New()
 	upgrader = websocket.Upgrader{}
+	dedup    *bool
+	codec    *string
+	keyFile  *string
+
+	tokenFile  *string
+	policyFile *string
+	auditFile  *string
+
+	tlsCert *string
+	tlsKey  *string
+	tlsCA   *string
+
+	uploadParallelism   *int
+	downloadParallelism *int
+	downloadMemory      *int64
 )
 
+func init() {
+	dedup = flag.Bool("dedup", false, "split uploads to content-defined chunks, which are stored once per cluster")
+	codec = flag.String("codec", compression.None, "default compression codec of stored fragments: none, gzip or zstd")
+	keyFile = flag.String("key-file", "", "master key file, new files are encrypted at rest when set")
+	tokenFile = flag.String("token-file", "", "API token file, requests must have a token when set")
+	policyFile = flag.String("policy-file", "", "authorization policy file, requests are checked against its rules when set")
+	auditFile = flag.String("audit-file", "", "file, which denied requests are appended to, they are logged to stderr when not set")
+	tlsCert = flag.String("tls-cert", "", "certificate file of gRPC server, bucket servers are connected with mutual TLS when set")
+	tlsKey = flag.String("tls-key", "", "private key file of the gRPC server certificate")
+	tlsCA = flag.String("tls-ca", "", "CA file, which certificates of bucket servers are verified with")
+	uploadParallelism = flag.Int("upload-parallelism", 4, "number of fragments, sent to bucket servers at once by an upload")
+	downloadParallelism = flag.Int("download-parallelism", 4, "number of fragments, fetched from bucket servers at once by a download")
+	downloadMemory = flag.Int64("download-memory", 64<<20, "memory limit in bytes for prefetched fragments of a download")
+}
+
 func main() {
+	flag.Parse()
+
 	server := NewApiServer()
 
 	err := http.ListenAndServe("0.0.0.0:80", server.Router)
@@ -64,12 +135,76 @@ func NewApiServer() *ApiServer {
 		log.WithError(err).Fatalln("failed to create fragment registry")
 	}
 
+	defaultCodec, err := compression.Parse(*codec, compression.None)
+	if err != nil {
+		log.WithError(err).Fatalln("invalid compression codec")
+	}
+
+	if *uploadParallelism < 1 || *downloadParallelism < 1 || *downloadMemory < 0 {
+		log.Fatalln("upload and download parallelism must be positive, memory limit must not be negative")
+	}
+
 	s := &ApiServer{
 		bucketRegistry:   registry.NewRegistry(),
 		fragmentRegistry: fr,
-		cleanupTicker:    time.NewTicker(cleanupInterval),
+		fragmentPlanner: fragment.Planner{
+			Fragments:       serverNumber,
+			MinFragmentSize: minFragmentSize,
+			MaxFragmentSize: maxFragmentSize,
+		},
+		dedup:               *dedup,
+		codec:               defaultCodec,
+		uploadParallelism:   *uploadParallelism,
+		downloadParallelism: *downloadParallelism,
+		downloadMemory:      *downloadMemory,
+		cleanupTicker:       time.NewTicker(cleanupInterval),
+	}
+
+	if len(*keyFile) > 0 {
+		s.keyring, err = encryption.LoadKeyring(*keyFile)
+		if err != nil {
+			log.WithError(err).Fatalln("failed to load master key file")
+		}
 	}
 
+	if len(*tokenFile) > 0 {
+		s.tokens, err = auth.LoadTokens(*tokenFile)
+		if err != nil {
+			log.WithError(err).Fatalln("failed to load token file")
+		}
+	}
+
+	if len(*policyFile) > 0 {
+		s.policy, err = auth.LoadPolicy(*policyFile)
+		if err != nil {
+			log.WithError(err).Fatalln("failed to load policy file")
+		}
+
+		go s.watchPolicy()
+	}
+
+	s.audit = logrus.New()
+	s.audit.SetFormatter(&logrus.JSONFormatter{})
+	if len(*auditFile) > 0 {
+		f, err := os.OpenFile(*auditFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			log.WithError(err).Fatalln("failed to open audit file")
+		}
+
+		s.audit.SetOutput(f)
+	}
+
+	if len(*tlsCert) > 0 || len(*tlsKey) > 0 || len(*tlsCA) > 0 {
+		s.credentials, err = mtls.Load(*tlsCert, *tlsKey, *tlsCA)
+		if err != nil {
+			log.WithError(err).Fatalln("failed to load TLS credentials")
+		}
+
+		go s.watchCredentials()
+	}
+
+	s.connections = newConnectionPool(s.bucketRegistry, s.credentials)
+
 	s.initRouter()
 	s.initGRPCServer()
 
@@ -84,7 +219,13 @@ func (s *ApiServer) initGRPCServer() {
 		log.WithError(err).Fatalln("failed to setup gRPC network listener")
 	}
 
-	grpcServer := grpc.NewServer()
+	var opts []grpc.ServerOption
+	if s.credentials != nil {
+		// any bucket server could connect, its certificate is checked against the address it registers
+		opts = append(opts, grpc.Creds(s.credentials.ServerCredentials("")))
+	}
+
+	grpcServer := grpc.NewServer(opts...)
 
 	bucket.RegisterApiServiceServer(grpcServer, s)
 	healthgrpc.RegisterHealthServer(grpcServer, health.NewServer())
@@ -98,25 +239,53 @@ func (s *ApiServer) initGRPCServer() {
 }
 
 func (s *ApiServer) RegisterBucket(ctx context.Context, r *bucket.RegisterBucketRequest) (*bucket.RegisterBucketResponse, error) {
+	err := s.verifyBucket(ctx, r.GetAddress())
+	if err != nil {
+		return nil, err
+	}
+
 	b := &registry.Server{
 		Address: r.GetAddress(),
 	}
-	err := s.bucketRegistry.Register(b)
+	err = s.bucketRegistry.Register(b)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to register bucket server: %v", err)
 	}
 
+	// connection is established right away, so bucket server health is known before it is used
+	_, err = s.connections.client(r.GetAddress())
+	if err != nil {
+		log.WithError(err).WithField("server", r.GetAddress()).Warn("failed to connect to bucket server")
+	}
+
 	log.WithField("server", r.GetAddress()).Info("server is registred")
 
 	return &bucket.RegisterBucketResponse{}, nil
 }
 
+func (s *ApiServer) DeregisterBucket(ctx context.Context, r *bucket.DeregisterBucketRequest) (*bucket.DeregisterBucketResponse, error) {
+	err := s.verifyBucket(ctx, r.GetAddress())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.bucketRegistry.Deregister(r.GetAddress())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "failed to deregister bucket server: %v", err)
+	}
+
+	s.connections.evict(r.GetAddress())
+
+	log.WithField("server", r.GetAddress()).Info("server is deregistered")
+
+	return &bucket.DeregisterBucketResponse{}, nil
+}
+
 func (s *ApiServer) deleteFragment(filename, address string, fragment int) error {
-	grpcConn, grpcClient, err := s.getBucketServerGRPCClient(address)
+	grpcClient, err := s.bucketClient(address)
 	if err != nil {
 		return err
 	}
-	defer grpcConn.Close()
 
 	_, err = grpcClient.DeleteFragment(context.Background(), &bucket.DeleteFragmentRequest{
 		Filename: filename,
@@ -126,55 +295,199 @@ func (s *ApiServer) deleteFragment(filename, address string, fragment int) error
 	return err
 }
 
-func (s *ApiServer) cleanup() {
-	for range s.cleanupTicker.C {
-		for filename, fileInfo := range s.fragmentRegistry.Files {
-			if fileInfo.Status != fragment.UploadStatusFailed {
+func (s *ApiServer) statFragment(filename, address string, fragment int) (int64, error) {
+	grpcClient, err := s.bucketClient(address)
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := grpcClient.StatFragment(context.Background(), &bucket.StatFragmentRequest{
+		Filename: filename,
+		Fragment: uint32(fragment),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return response.GetSize(), nil
+}
+
+// sizeFiles records sizes of files, uploaded before fragment sizes were recorded, with fragment sizes
+// reported by bucket servers, so the files are counted by namespace quotas.
+func (s *ApiServer) sizeFiles() {
+	for _, fileInfo := range s.fragmentRegistry.Unsized() {
+		key := fileInfo.StorageKey()
+		fields := logrus.Fields{"namespace": fileInfo.Namespace, "filename": fileInfo.Name}
+
+		lengths := make([]int64, 0, len(fileInfo.Fragments))
+		for _, f := range fileInfo.Fragments {
+			if f.Length >= 0 {
+				lengths = append(lengths, f.Length)
 				continue
 			}
 
-			log.WithField("filename", filename).Info("cleaning up failed upload")
-			for i := range fileInfo.Addresses {
-				log.WithField("address", fileInfo.Addresses[i]).Infof("cleaning up %d fragment", i)
-				err := s.deleteFragment(filename, fileInfo.Addresses[i], i)
-				if err != nil {
-					log.WithError(err).Errorf("failed to delete fragment")
+			var length int64
+			err := fmt.Errorf("%d fragment has no replicas", f.Index)
+			for _, address := range f.Replicas {
+				length, err = s.statFragment(key, address, f.Index)
+				if err == nil {
 					break
 				}
 			}
+			if err != nil {
+				log.WithError(err).WithFields(fields).Warnf("failed to get size of %d fragment", f.Index)
+				break
+			}
 
-			err := s.fragmentRegistry.Delete(filename)
+			lengths = append(lengths, length)
+		}
+
+		// the rest of fragments is measured by the next cleanup
+		if len(lengths) < len(fileInfo.Fragments) {
+			continue
+		}
+
+		err := s.fragmentRegistry.SetFragmentLengths(fileInfo.Namespace, fileInfo.Name, key, lengths)
+		if err != nil {
+			log.WithError(err).WithFields(fields).Error("failed to record fragment sizes")
+			continue
+		}
+
+		log.WithFields(fields).Info("file size is recorded")
+	}
+}
+
+func (s *ApiServer) cleanup() {
+	for range s.cleanupTicker.C {
+		s.connections.closeStale(time.Now())
+
+		s.sizeFiles()
+
+		err := s.fragmentRegistry.Expire(uploadSessionTTL)
+		if err != nil {
+			log.WithError(err).Error("failed to expire abandoned uploads")
+		}
+
+		expired, err := s.fragmentRegistry.ExpireFiles()
+		if err != nil {
+			log.WithError(err).Error("failed to expire files")
+		}
+		if expired > 0 {
+			log.Infof("%d files are expired by retention of their namespaces", expired)
+		}
+
+		for _, fileInfo := range s.fragmentRegistry.Obsolete() {
+			key := fileInfo.StorageKey()
+
+			log.WithFields(logrus.Fields{"filename": fileInfo.Name, "key": key}).Info("cleaning up obsolete fragments")
+
+			var err error
+		fragments:
+			for _, f := range fileInfo.Fragments {
+				for _, address := range f.Replicas {
+					log.WithField("address", address).Infof("cleaning up %d fragment", f.Index)
+					err = s.deleteFragment(key, address, f.Index)
+					if err != nil {
+						log.WithError(err).Errorf("failed to delete fragment")
+						break fragments
+					}
+				}
+			}
+
+			if err != nil {
+				continue
+			}
+
+			err = s.fragmentRegistry.Purge(key)
 			if err != nil {
 				log.WithError(err).Errorf("failed to delete fragment from registry")
 				continue
 			}
 
-			log.WithField("filename", filename).Info("cleaning up failed upload succeeded")
+			log.WithField("filename", fileInfo.Name).Info("cleaning up obsolete fragments succeeded")
+		}
+
+		for _, chunk := range s.fragmentRegistry.DeletingChunks() {
+			err := s.deleteFragment(chunk.StorageKey(), chunk.Address, 0)
+			if err != nil {
+				log.WithError(err).WithFields(logrus.Fields{"hash": chunk.Hash, "address": chunk.Address}).Error("failed to delete chunk")
+				continue
+			}
+
+			err = s.fragmentRegistry.ForgetChunk(chunk.Hash)
+			if err != nil {
+				log.WithError(err).WithField("hash", chunk.Hash).Error("failed to delete chunk from registry")
+			}
 		}
 	}
 }
 
 func (s *ApiServer) initRouter() {
 	router := mux.NewRouter()
+	router.Use(s.authenticate)
+
+	router.HandleFunc("/ns", s.authorize(auth.ActionAdmin, s.listNamespaces)).Methods(http.MethodGet)
+	router.HandleFunc("/ns/{namespace}", s.authorize(auth.ActionAdmin, s.getNamespace)).Methods(http.MethodGet)
+	router.HandleFunc("/ns/{namespace}", s.authorize(auth.ActionAdmin, s.putNamespace)).Methods(http.MethodPut)
+	router.HandleFunc("/ns/{namespace}", s.authorize(auth.ActionAdmin, s.deleteNamespace)).Methods(http.MethodDelete)
+
+	// file endpoints without namespace prefix work with the default namespace
+	for _, files := range []*mux.Router{router, router.PathPrefix("/ns/{namespace}").Subrouter()} {
+		files.HandleFunc("/upload/{filename}", s.authorize(auth.ActionUpload, s.upload))
+		files.HandleFunc("/download/{filename}", s.authorize(auth.ActionDownload, s.download))
+		files.HandleFunc("/objects/{filename}", s.authorize(auth.ActionUpload, s.putObject)).Methods(http.MethodPut)
+		files.HandleFunc("/objects/{filename}", s.authorize(auth.ActionDownload, s.getObject)).Methods(http.MethodGet, http.MethodHead)
+		files.HandleFunc("/files", s.authorize(auth.ActionList, s.listFiles)).Methods(http.MethodGet)
+		files.HandleFunc("/files/{filename}", s.authorize(auth.ActionDownload, s.statFile)).Methods(http.MethodGet)
+		files.HandleFunc("/files/{filename}", s.authorize(auth.ActionDelete, s.deleteFile)).Methods(http.MethodDelete)
+	}
 
-	router.HandleFunc("/upload/{filename}", s.upload)
-	router.HandleFunc("/download/{filename}", s.download)
+	router.HandleFunc("/keys/rotate", s.authorize(auth.ActionAdmin, s.rotateKeys)).Methods(http.MethodPost)
+	router.HandleFunc("/buckets", s.authorize(auth.ActionAdmin, s.listBuckets)).Methods(http.MethodGet)
 
 	s.Router = router
 }
 
-func (s *ApiServer) getBucketServerGRPCClient(address string) (*grpc.ClientConn, bucket.BucketServiceClient, error) {
-	insecureCreds := grpc.WithTransportCredentials(insecure.NewCredentials())
+func (s *ApiServer) bucketClient(address string) (bucket.BucketServiceClient, error) {
+	return s.connections.client(address)
+}
 
-	grpcConn, err := grpc.NewClient(address, insecureCreds)
-	if err != nil {
-		return nil, nil, err
+// replicaAddress returns the first replica of the fragment on a healthy bucket server, so the fragment
+// is still downloaded, when one of bucket servers storing it fails.
+func (s *ApiServer) replicaAddress(fr fragment.FragmentRange) string {
+	if len(fr.Replicas) < 2 {
+		return fr.Address
+	}
+
+	healthy := make(map[string]bool)
+	for _, server := range s.bucketRegistry.Servers() {
+		healthy[server.Address] = !server.Unhealthy
 	}
 
-	return grpcConn, bucket.NewBucketServiceClient(grpcConn), nil
+	for _, address := range fr.Replicas {
+		if healthy[address] {
+			return address
+		}
+	}
+
+	return fr.Address
 }
 
 func (s *ApiServer) chooseServer(filename string, totalBytes int64, payload []byte) *registry.Server {
+	return s.bucketRegistry.GetServer(placementHash(filename, totalBytes, payload))
+}
+
+// the next servers in the ring replace the chosen one, when it fails
+func (s *ApiServer) chooseServers(filename string, totalBytes int64, payload []byte) []string {
+	var addresses []string
+	for _, server := range s.bucketRegistry.Ring(placementHash(filename, totalBytes, payload)) {
+		addresses = append(addresses, server.Address)
+	}
+
+	return addresses
+}
+
+func placementHash(filename string, totalBytes int64, payload []byte) hash.Hash64 {
 	bInt64 := make([]byte, 8)
 
 	h := fnv.New64()
@@ -183,21 +496,55 @@ func (s *ApiServer) chooseServer(filename string, totalBytes int64, payload []by
 	h.Write(bInt64)
 	h.Write(payload)
 
-	return s.bucketRegistry.GetServer(h)
+	return h
 }
 
-func (s *ApiServer) readUploadFileSize(conn *websocket.Conn) (int64, error) {
+func (s *ApiServer) readUploadFileInfo(conn *websocket.Conn) (*bucket.WsFileInfo, error) {
 	var fileInfo bucket.WsFileInfo
 	err := conn.ReadJSON(&fileInfo)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	if fileInfo.GetSize() < 0 {
+		return nil, newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, errors.New("invalid file size"))
+	}
+
+	return &fileInfo, nil
+}
+
+// uploadSession resumes upload requested by the client or begins a new one,
+// when there is nothing to resume.
+func (s *ApiServer) uploadSession(namespace, filename string, fileInfo *bucket.WsFileInfo) (*fragment.FileMeta, error) {
+	codec, err := compression.Parse(fileInfo.GetCodec(), s.codec)
+	if err != nil {
+		return nil, newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, err)
+	}
+
+	metadata, err := validateMetadata(fileInfo.GetMetadata())
+	if err != nil {
+		return nil, err
+	}
+
+	opts := fragment.UploadOptions{
+		Exclusive:   fileInfo.GetExclusive(),
+		Checksum:    fileInfo.GetChecksum(),
+		ContentType: fileInfo.GetContentType(),
+		Metadata:    metadata,
+		Codec:       codec,
+	}
+
+	if len(fileInfo.GetUploadId()) == 0 {
+		return s.beginUpload(namespace, filename, fileInfo.GetSize(), opts)
 	}
 
-	if fileInfo.GetSize() == 0 {
-		return 0, errors.New("empty file")
+	session, err := s.fragmentRegistry.ResumeUpload(fileInfo.GetUploadId(), namespace, filename, fileInfo.GetSize())
+	if errors.Is(err, fragment.ErrUploadNotFound) {
+		log.WithField("upload_id", fileInfo.GetUploadId()).Warn("upload to resume is not found, starting a new one")
+		return s.beginUpload(namespace, filename, fileInfo.GetSize(), opts)
 	}
 
-	return fileInfo.GetSize(), nil
+	return session, err
 }
 
 func (s *ApiServer) upload(w http.ResponseWriter, r *http.Request) {
@@ -217,118 +564,356 @@ func (s *ApiServer) upload(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
-	fileSize, err := s.readUploadFileSize(conn)
+	fileInfo, err := s.readUploadFileInfo(conn)
 	if err != nil {
 		log.WithError(err).Error("failed to read file info")
+		closeWithError(conn, err)
 		return
 	}
-	chunkSize := fileSize / serverNumber
 
-	log.Infof("uploading file %s with %d size, splitting to chunk with %d", filename, fileSize, chunkSize)
+	session, err := s.uploadSession(requestNamespace(r), filename, fileInfo)
+	if err != nil {
+		log.WithError(err).WithField("upload_id", fileInfo.GetUploadId()).Error("failed to stage upload")
+		closeWithError(conn, err)
+		return
+	}
 
-	var (
-		totalBytes, currentChunkSize int64
-		grpcConn                     *grpc.ClientConn
-		grpcClient                   bucket.BucketServiceClient
-		bucketServer                 *registry.Server
-		grpcStream                   bucket.BucketService_UploadChunksClient
-	)
+	framed := fileInfo.GetVersion() == bucket.WsProtocolVersion_WS_PROTOCOL_FRAMED
 
-	uploadStatus := fragment.UploadStatusFailed
-	defer func() {
-		err = s.fragmentRegistry.AddFragment(filename, bucketServer.Address)
+	// framed uploads are always resumable
+	resumable := fileInfo.GetResumable() || framed
+
+	var source chunkReader = &wsChunkReader{conn: conn}
+	if resumable {
+		uploadSession := &bucket.WsUploadSession{
+			UploadId: session.UploadID,
+			Offset:   session.Committed(),
+		}
+
+		if framed {
+			uploadSession.Version = bucket.WsProtocolVersion_WS_PROTOCOL_FRAMED
+			uploadSession.Window = framedWindow
+			source = newWsFramedChunkReader(conn, session.Committed())
+		}
+
+		err = conn.WriteJSON(uploadSession)
 		if err != nil {
-			log.WithError(err).Error("failed to update registry record")
+			log.WithError(err).Error("failed to send upload session")
+			s.fragmentRegistry.Detach(session.UploadID)
 			return
 		}
+	}
+
+	checksum, err := s.store(r.Context(), session, resumable, source)
+	if err != nil {
+		log.WithError(err).WithField("filename", filename).Error("failed to upload file")
+	}
 
-		err = s.fragmentRegistry.SetStatus(filename, uploadStatus)
+	if !framed {
 		if err != nil {
-			log.WithError(err).Errorf("failed to set upload status")
-			return
+			closeWithError(conn, err)
 		}
-	}()
+		return
+	}
 
+	uploadStatus := &bucket.WsUploadStatus{
+		Status:   fragment.StatusName(fragment.UploadStatusComplete),
+		Size:     session.Size,
+		Checksum: checksum,
+	}
+	if err != nil {
+		uploadStatus = &bucket.WsUploadStatus{
+			Status: fragment.StatusName(fragment.UploadStatusIncomplete),
+			Error:  err.Error(),
+		}
+
+		// upload, which can't be completed, is failed
+		if code := errorCode(err); code == bucket.WsErrorCode_WS_ERROR_CHECKSUM_MISMATCH || code == bucket.WsErrorCode_WS_ERROR_FILE_EXISTS {
+			uploadStatus.Status = fragment.StatusName(fragment.UploadStatusFailed)
+		}
+	}
+
+	storeErr := err
+	msg := &bucket.WsServerMessage{Status: uploadStatus}
+	if storeErr != nil {
+		msg.Error = wsError(storeErr)
+	}
+
+	err = conn.WriteJSON(msg)
+	if err != nil {
+		log.WithError(err).Error("failed to send upload status")
+		return
+	}
+
+	if storeErr != nil {
+		closeWithError(conn, storeErr)
+		return
+	}
+
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
+
+// store saves the upload as deduplicated chunks or fragments, depending on the server mode.
+func (s *ApiServer) store(ctx context.Context, session *fragment.FileMeta, resumable bool, source chunkReader) (string, error) {
+	if s.dedup {
+		return s.storeDedupFile(ctx, session, resumable, source)
+	}
+
+	return s.storeFile(ctx, session, resumable, source)
+}
+
+// restoreFileHash continues checksum calculation of resumed upload.
+func restoreFileHash(session *fragment.FileMeta) (hash.Hash, error) {
+	fileHash := sha256.New()
+	if len(session.HashState) > 0 {
+		err := fileHash.(encoding.BinaryUnmarshaler).UnmarshalBinary(session.HashState)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore file checksum state: %w", err)
+		}
+	}
+
+	return fileHash, nil
+}
+
+// completeUpload verifies checksum of the received file and makes it available under its name.
+func (s *ApiServer) completeUpload(session *fragment.FileMeta, fileHash hash.Hash) (string, error) {
+	checksum := hex.EncodeToString(fileHash.Sum(nil))
+	if len(session.Checksum) > 0 && session.Checksum != checksum {
+		err := s.fragmentRegistry.SetStatus(session.UploadID, fragment.UploadStatusFailed)
+		if err != nil {
+			log.WithError(err).Errorf("failed to update upload status")
+		}
+
+		return "", errChecksumMismatch
+	}
+
+	err := s.fragmentRegistry.SetChecksum(session.UploadID, checksum)
+	if err != nil {
+		return "", fmt.Errorf("failed to set file checksum: %w", err)
+	}
+
+	err = s.fragmentRegistry.SetStatus(session.UploadID, fragment.UploadStatusComplete)
+	if err != nil {
+		return "", fmt.Errorf("failed to complete upload: %w", err)
+	}
+
+	return checksum, nil
+}
+
+// detachUpload keeps interrupted resumable upload incomplete, otherwise the upload is failed.
+func (s *ApiServer) detachUpload(uploadID string, resumable bool) {
+	var err error
+	if resumable {
+		err = s.fragmentRegistry.Detach(uploadID)
+	} else {
+		err = s.fragmentRegistry.SetStatus(uploadID, fragment.UploadStatusFailed)
+	}
+	if err != nil {
+		log.WithError(err).Errorf("failed to update upload status")
+	}
+}
+
+// storeFile splits data, read from the source, to fragments and stores them on bucket servers.
+// The file is available under its name when upload is complete, its checksum is returned. Interrupted
+// resumable upload is kept incomplete with all fragments stored so far, otherwise it is failed.
+func (s *ApiServer) storeFile(ctx context.Context, session *fragment.FileMeta, resumable bool, source chunkReader) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	filename, uploadID, fileSize := session.Name, session.UploadID, session.Size
+
+	fileHash, err := restoreFileHash(session)
+	if err != nil {
+		s.fragmentRegistry.SetStatus(uploadID, fragment.UploadStatusFailed)
+		return "", err
+	}
+
+	dataKey, err := s.dataKey(session.DataKey)
+	if err != nil {
+		s.detachUpload(uploadID, resumable)
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	var (
+		committed    = session.Committed()
+		totalBytes   = committed
+		current      *fragmentUpload
+		pending      []*fragmentUpload // fragments, which are being stored, in order
+		fragmentHash hash.Hash
+		span         fragment.Span
+		spanBytes    int64
+	)
+
+	complete := false
 	defer func() {
-		if grpcConn == nil {
+		if complete {
 			return
 		}
 
-		grpcStream.CloseAndRecv()
-		grpcConn.Close()
+		// unfinished fragments are aborted, so bucket servers don't keep them
+		uploads := pending
+		if current != nil {
+			uploads = append(uploads, current)
+		}
+		s.abortFragments(cancel, uploadID, uploads)
+
+		s.detachUpload(uploadID, resumable)
 	}()
 
-	fragmentNumber := -1
-	for {
-		if totalBytes >= fileSize {
-			break
+	// only the rest of resumed upload is planned, stored fragments are kept as they are
+	spans, err := s.fragmentPlanner.Plan(fileSize-committed, s.bucketRegistry.Count())
+	if errors.Is(err, fragment.ErrNoBuckets) {
+		return "", errNoCapacity
+	}
+	if err != nil {
+		return "", newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, err)
+	}
+
+	log.Infof("uploading file %s (%s) with %d size from %d offset, splitting to %d fragments", filename, uploadID, fileSize, committed, len(spans))
+
+	fragmentNumber := len(session.Fragments)
+	resumedAt := committed
+
+	// uploads staged before replication was introduced keep a single copy of every fragment
+	replicas := max(session.Replication, 1)
+
+	// commitFragments registers stored fragments in order, waiting for them until at most keep fragments are left
+	commitFragments := func(keep int) error {
+		for len(pending) > 0 {
+			u := pending[0]
+			if len(pending) <= keep && !u.finished() {
+				return nil
+			}
+
+			err := u.wait()
+			if err != nil {
+				return err
+			}
+
+			err = s.fragmentRegistry.AddFragment(uploadID, u.replicas, u.size, u.checksum, u.hashState)
+			if err != nil {
+				return fmt.Errorf("failed to update registry record: %w", err)
+			}
+			pending = pending[1:]
+
+			committed += u.size
+			if notifier, ok := source.(commitNotifier); ok {
+				err = notifier.Committed(committed)
+				if err != nil {
+					return err
+				}
+			}
 		}
 
-		_, b, err := conn.ReadMessage()
-		if errors.Is(err, io.EOF) {
-			break
+		return nil
+	}
+
+	finishFragment := func() error {
+		hashState, err := fileHash.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("failed to save file checksum state: %w", err)
 		}
 
+		current.finish(spanBytes, hex.EncodeToString(fragmentHash.Sum(nil)), hashState)
+		pending = append(pending, current)
+		current = nil
+
+		return nil
+	}
+
+	startFragment := func(payload []byte) error {
+		err := commitFragments(s.uploadParallelism - 1)
 		if err != nil {
-			log.WithError(err).Error("failed to read chunk")
-			return
+			return err
+		}
+
+		span = spans[0]
+		spans = spans[1:]
+		spanBytes = 0
+		fragmentHash = sha256.New()
+
+		addresses := s.chooseServers(filename, resumedAt+span.Offset, payload)
+		if len(addresses) < replicas {
+			return errNoCapacity
 		}
 
-		if len(b) == 0 {
+		current = s.startFragmentUpload(ctx, uploadID, fragmentNumber, addresses, replicas, session.Codec, dataKey)
+
+		return nil
+	}
+
+	for {
+		b, err := source.ReadChunk()
+		if errors.Is(err, io.EOF) {
 			break
 		}
 
+		if err != nil {
+			return "", fmt.Errorf("failed to read chunk: %w", err)
+		}
+
+		if totalBytes+int64(len(b)) > fileSize {
+			return "", newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, fmt.Errorf("received more than %d bytes", fileSize))
+		}
 		totalBytes += int64(len(b))
-		currentChunkSize += int64(len(b))
 
-		if bucketServer == nil || currentChunkSize >= chunkSize {
-			if bucketServer != nil {
-				err = s.fragmentRegistry.AddFragment(filename, bucketServer.Address)
+		// chunk is split at fragment boundaries
+		for len(b) > 0 {
+			if current != nil && spanBytes == span.Length {
+				err = finishFragment()
 				if err != nil {
-					log.WithError(err).Error("failed to update registry record")
-					return
+					return "", err
 				}
-			}
 
-			fragmentNumber++
-			currentChunkSize = 0
-
-			bucketServer = s.chooseServer(filename, totalBytes, b)
-
-			if grpcConn != nil {
-				grpcStream.CloseAndRecv()
-				grpcConn.Close()
+				fragmentNumber++
 			}
 
-			grpcConn, grpcClient, err = s.getBucketServerGRPCClient(bucketServer.Address)
-			if err != nil {
-				log.WithError(err).WithField("address", bucketServer.Address).Errorf("failed to init bucket server GRPC client")
-				return
+			if current == nil {
+				err = startFragment(b)
+				if err != nil {
+					return "", err
+				}
 			}
 
-			grpcStream, err = grpcClient.UploadChunks(r.Context())
+			n := min(int64(len(b)), span.Length-spanBytes)
+
+			// source could reuse the chunk buffer, while the fragment is still sent
+			err = current.write(append([]byte(nil), b[:n]...))
 			if err != nil {
-				log.WithError(err).Errorf("failed to upload chunks to %s", bucketServer.Address)
-				return
+				return "", err
 			}
-		}
 
-		chunk := &bucket.UploadChunk{
-			Filename: filename,
-			Fragment: uint32(fragmentNumber),
-			Chunk: &bucket.Chunk{
-				Data: b,
-			},
+			fragmentHash.Write(b[:n])
+			fileHash.Write(b[:n])
+			spanBytes += n
+			b = b[n:]
 		}
+	}
+
+	if totalBytes != fileSize {
+		return "", fmt.Errorf("received %d bytes, %d expected", totalBytes, fileSize)
+	}
 
-		err = grpcStream.Send(chunk)
+	if current != nil {
+		err := finishFragment()
 		if err != nil {
-			log.WithError(err).Errorf("failed to send chunk to %s", bucketServer.Address)
-			return
+			return "", err
 		}
 	}
 
-	uploadStatus = fragment.UploadStatusComplete
+	err = commitFragments(0)
+	if err != nil {
+		return "", err
+	}
+
+	checksum, err := s.completeUpload(session, fileHash)
+	if err != nil {
+		return "", err
+	}
+
+	complete = true
+
+	return checksum, nil
 }
 
 func (s *ApiServer) download(w http.ResponseWriter, r *http.Request) {
@@ -340,19 +925,30 @@ func (s *ApiServer) download(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	meta, ok := s.fragmentRegistry.Files[filename]
+	meta, ok := s.fragmentRegistry.Acquire(requestNamespace(r), filename)
 	if !ok {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	defer s.fragmentRegistry.Release(meta)
 
-	if meta.Status != fragment.UploadStatusComplete {
-		w.WriteHeader(http.StatusNotFound)
-		log.WithField("filename", filename).Error("file upload was incomplete")
+	ranges, err := downloadRanges(meta, r.URL.Query())
+	if err != nil {
+		log.WithError(err).WithField("filename", filename).Error("failed to map download range")
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
 		return
 	}
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	// file version is sent with the response, so interrupted download could be resumed
+	header := http.Header{}
+	header.Set(sizeHeader, strconv.FormatInt(meta.Size, 10))
+	if len(meta.Checksum) > 0 {
+		header.Set(checksumHeader, meta.Checksum)
+	}
+	header.Set(contentTypeHeader, contentType(meta))
+	setMetadataHeaders(header, meta)
+
+	conn, err := upgrader.Upgrade(w, r, header)
 	if err != nil {
 		log.WithError(err).Error("can't upgrade connection to websocket")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -360,57 +956,66 @@ func (s *ApiServer) download(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
-	var (
-		grpcConn   *grpc.ClientConn
-		grpcClient bucket.BucketServiceClient
-	)
+	err = s.loadFile(r.Context(), meta, ranges, &wsChunkWriter{conn: conn})
+	if err != nil {
+		log.WithError(err).WithField("filename", filename).Error("failed to download file")
+		return
+	}
 
-	defer func() {
-		if grpcConn != nil {
-			grpcConn.Close()
-		}
-	}()
-	for i, server := range meta.Addresses {
-		if grpcConn != nil {
-			grpcConn.Close()
-		}
+	err = conn.WriteMessage(websocket.BinaryMessage, []byte{})
+	if err != nil {
+		log.WithError(err).Error("failed to sent chunk")
+	}
+}
+
+// loadFile reads fragment ranges from bucket servers one by one and writes them to the destination.
+func (s *ApiServer) loadFile(ctx context.Context, meta *fragment.FileMeta, ranges []fragment.FragmentRange, destination chunkWriter) error {
+	if s.downloadParallelism > 1 && len(ranges) > 1 {
+		return s.loadFileParallel(ctx, ranges, destination)
+	}
+
+	for _, fr := range ranges {
+		fr.Address = s.replicaAddress(fr)
 
-		grpcConn, grpcClient, err = s.getBucketServerGRPCClient(server)
+		grpcClient, err := s.bucketClient(fr.Address)
 		if err != nil {
-			log.WithError(err).Errorf("failed to connect to %s", server)
-			return
+			return fmt.Errorf("failed to connect to %s: %w", fr.Address, err)
 		}
 
-		grpcStream, err := grpcClient.DownloadChunks(r.Context(), &bucket.DownloadRequest{
-			Filename: filename,
-			Fragment: uint32(i),
-		})
+		err = s.loadRange(ctx, grpcClient, fr, destination)
 		if err != nil {
-			log.WithError(err).Error("failed to start file fragment downloading")
-			return
+			return err
 		}
+	}
 
-		for {
-			chunk, err := grpcStream.Recv()
-			if errors.Is(err, io.EOF) {
-				break
-			}
+	return nil
+}
 
-			if err != nil {
-				log.WithError(err).Error("failed to download chunk")
-				return
-			}
+// downloadRanges returns fragment ranges for offset and length query parameters of the download request.
+// The whole file is downloaded, when neither is set.
+func downloadRanges(meta *fragment.FileMeta, query url.Values) ([]fragment.FragmentRange, error) {
+	if !query.Has("offset") && !query.Has("length") {
+		return meta.AllRanges(), nil
+	}
 
-			err = conn.WriteMessage(websocket.BinaryMessage, chunk.GetData())
-			if err != nil {
-				log.WithError(err).Error("failed to sent chunk")
-				return
-			}
+	var (
+		offset int64
+		err    error
+	)
+	if query.Has("offset") {
+		offset, err = strconv.ParseInt(query.Get("offset"), 10, 64)
+		if err != nil {
+			return nil, fragment.ErrInvalidRange
 		}
 	}
 
-	err = conn.WriteMessage(websocket.BinaryMessage, []byte{})
-	if err != nil {
-		log.WithError(err).Error("failed to sent chunk")
+	length := meta.Size - offset
+	if query.Has("length") {
+		length, err = strconv.ParseInt(query.Get("length"), 10, 64)
+		if err != nil {
+			return nil, fragment.ErrInvalidRange
+		}
 	}
+
+	return meta.Ranges(offset, length)
 }
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+	"github.com/aburluka/k8test/internal/registry"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testBucket struct {
+	address string
+
+	lock      sync.Mutex
+	fragments map[string][]byte
+	// corrupt bucket loses the last byte of every stored fragment
+	corrupt bool
+
+	// onChunk is called with the number of fragment bytes received so far, its error breaks the upload
+	onChunk func(fragment uint32, received int) error
+	// onUpload is called when the fragment is received, its error fails the upload
+	onUpload func(ctx context.Context, filename string, fragment uint32) error
+	// onDownload is called before the fragment is sent, its error fails the download
+	onDownload func(ctx context.Context, r *bucket.DownloadRequest) error
+
+	bucket.UnimplementedBucketServiceServer
+}
+
+func fragmentKey(filename string, fragment uint32) string {
+	return fmt.Sprintf("%s/%d", filename, fragment)
+}
+
+func startTestBucket(t *testing.T) *testBucket {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &testBucket{
+		address:   listener.Addr().String(),
+		fragments: make(map[string][]byte),
+	}
+
+	server := grpc.NewServer()
+	bucket.RegisterBucketServiceServer(server, b)
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return b
+}
+
+func (b *testBucket) put(filename string, fragment uint32, data []byte) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.fragments[fragmentKey(filename, fragment)] = data
+}
+
+func (b *testBucket) get(filename string, fragment uint32) ([]byte, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	data, ok := b.fragments[fragmentKey(filename, fragment)]
+	return data, ok
+}
+
+// storedFragments returns numbers of the stored fragments of the file.
+func (b *testBucket) storedFragments(filename string) []uint32 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var stored []uint32
+	for fragment := uint32(0); fragment < serverNumber; fragment++ {
+		if _, ok := b.fragments[fragmentKey(filename, fragment)]; ok {
+			stored = append(stored, fragment)
+		}
+	}
+
+	return stored
+}
+
+func (b *testBucket) storedBytes() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var n int
+	for _, data := range b.fragments {
+		n += len(data)
+	}
+
+	return n
+}
+
+func (b *testBucket) UploadChunks(stream bucket.BucketService_UploadChunksServer) error {
+	var (
+		data     bytes.Buffer
+		filename string
+		fragment uint32
+	)
+
+	b.lock.Lock()
+	onChunk := b.onChunk
+	b.lock.Unlock()
+
+	for {
+		request, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		data.Write(request.GetChunk().GetData())
+		filename = request.GetFilename()
+		fragment = request.GetFragment()
+
+		if onChunk != nil {
+			err = onChunk(fragment, data.Len())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	b.lock.Lock()
+	onUpload, corrupt := b.onUpload, b.corrupt
+	b.lock.Unlock()
+
+	if onUpload != nil {
+		err := onUpload(stream.Context(), filename, fragment)
+		if err != nil {
+			return err
+		}
+	}
+
+	stored := data.Bytes()
+	if corrupt && len(stored) > 0 {
+		stored = stored[:len(stored)-1]
+	}
+	b.put(filename, fragment, stored)
+
+	checksum := sha256.Sum256(stored)
+
+	return stream.SendAndClose(&bucket.UploadResponse{
+		Size:     int64(len(stored)),
+		Checksum: hex.EncodeToString(checksum[:]),
+	})
+}
+
+func (b *testBucket) setOnChunk(f func(fragment uint32, received int) error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.onChunk = f
+}
+
+func (b *testBucket) setOnUpload(f func(ctx context.Context, filename string, fragment uint32) error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.onUpload = f
+}
+
+func (b *testBucket) setOnDownload(f func(ctx context.Context, r *bucket.DownloadRequest) error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.onDownload = f
+}
+
+func (b *testBucket) DownloadChunks(r *bucket.DownloadRequest, stream bucket.BucketService_DownloadChunksServer) error {
+	b.lock.Lock()
+	onDownload := b.onDownload
+	b.lock.Unlock()
+
+	if onDownload != nil {
+		err := onDownload(stream.Context(), r)
+		if err != nil {
+			return err
+		}
+	}
+
+	data, ok := b.get(r.GetFilename(), r.GetFragment())
+	if !ok {
+		return status.Error(codes.NotFound, "fragment is not found")
+	}
+
+	if r.GetOffset() > int64(len(data)) {
+		return status.Error(codes.OutOfRange, "invalid offset")
+	}
+
+	data = data[r.GetOffset():]
+	if r.GetLength() > 0 {
+		data = data[:min(r.GetLength(), int64(len(data)))]
+	}
+
+	for len(data) > 0 {
+		n := min(len(data), 1000)
+
+		err := stream.Send(&bucket.Chunk{Data: data[:n]})
+		if err != nil {
+			return err
+		}
+
+		data = data[n:]
+	}
+
+	return nil
+}
+
+func (b *testBucket) DeleteFragment(ctx context.Context, r *bucket.DeleteFragmentRequest) (*bucket.DeleteFragmentResponse, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.fragments, fragmentKey(r.GetFilename(), r.GetFragment()))
+
+	return &bucket.DeleteFragmentResponse{}, nil
+}
+
+// useTestDir switches to a temporary directory, where the fragment registry keeps its file.
+func useTestDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	return dir
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func newTestServer(t *testing.T, buckets int) (*ApiServer, []*testBucket) {
+	t.Helper()
+
+	useTestDir(t)
+
+	fr, err := fragment.NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	s := &ApiServer{
+		bucketRegistry:   registry.NewRegistry(),
+		fragmentRegistry: fr,
+		// small fragments to split test files
+		fragmentPlanner: fragment.Planner{
+			Fragments:       serverNumber,
+			MinFragmentSize: 1000,
+			MaxFragmentSize: maxFragmentSize,
+		},
+	}
+
+	s.connections = newConnectionPool(s.bucketRegistry, nil)
+
+	var started []*testBucket
+	for range buckets {
+		b := startTestBucket(t)
+
+		err = s.bucketRegistry.Register(&registry.Server{Address: b.address})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.connections.evict(b.address) })
+
+		started = append(started, b)
+	}
+
+	s.initRouter()
+
+	return s, started
+}
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+
+	return data
+}
+
+func serve(s *ApiServer, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, r)
+
+	return w
+}
+
+func putTestObject(t *testing.T, s *ApiServer, filename string, data []byte) {
+	t.Helper()
+
+	w := serve(s, httptest.NewRequest(http.MethodPut, "/objects/"+filename, bytes.NewReader(data)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("PUT /objects/%s status = %d, want %d", filename, w.Code, http.StatusCreated)
+	}
+}
+
+func dialUpload(t *testing.T, s *ApiServer, filename string, fileInfo *bucket.WsFileInfo) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(s.Router)
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/upload/"+filename, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	err = conn.WriteJSON(fileInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return conn
+}
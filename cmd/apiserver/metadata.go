@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+)
+
+const (
+	// user metadata of REST upload and download is sent in X-File-Meta-{key} headers
+	metadataHeaderPrefix = "X-File-Meta-"
+
+	// content type of WebSocket download is sent in the handshake response
+	contentTypeHeader = "X-File-Content-Type"
+
+	// metadata is kept in the registry with every file, so it must stay small
+	maxMetadataSize = 8 << 10
+)
+
+// validateMetadata normalizes metadata keys to lower case, since they are sent as HTTP headers,
+// and checks that metadata fits the limit.
+func validateMetadata(metadata map[string]string) (map[string]string, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	normalized := make(map[string]string, len(metadata))
+	size := 0
+	for key, value := range metadata {
+		key = strings.ToLower(key)
+		if !validMetadataKey(key) {
+			return nil, newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, fmt.Errorf("invalid metadata key %q", key))
+		}
+
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, fmt.Errorf("invalid value of metadata key %q", key))
+		}
+
+		if _, ok := normalized[key]; ok {
+			return nil, newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, fmt.Errorf("duplicate metadata key %q", key))
+		}
+
+		normalized[key] = value
+		size += len(key) + len(value)
+	}
+
+	if size > maxMetadataSize {
+		return nil, newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, errors.New("metadata is too large"))
+	}
+
+	return normalized, nil
+}
+
+// validMetadataKey reports whether the key could be sent as a part of HTTP header name.
+func validMetadataKey(key string) bool {
+	if len(key) == 0 {
+		return false
+	}
+
+	for _, c := range key {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' && c != '.' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// requestMetadata collects user metadata from X-File-Meta-{key} headers of REST upload.
+func requestMetadata(header http.Header) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for name, values := range header {
+		key, ok := strings.CutPrefix(name, metadataHeaderPrefix)
+		if !ok {
+			continue
+		}
+
+		metadata[key] = strings.Join(values, ",")
+	}
+
+	return validateMetadata(metadata)
+}
+
+// setMetadataHeaders sends user metadata of the file in X-File-Meta-{key} headers.
+func setMetadataHeaders(header http.Header, meta *fragment.FileMeta) {
+	for key, value := range meta.Metadata {
+		header.Set(metadataHeaderPrefix+key, value)
+	}
+}
+
+// metadataFilter builds file filter of list request from meta query parameters: "key" requires
+// the file to have the metadata key, "key=value" requires the value as well.
+func metadataFilter(query url.Values) func(*fragment.FileMeta) bool {
+	conditions := query["meta"]
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	return func(meta *fragment.FileMeta) bool {
+		for _, condition := range conditions {
+			key, value, withValue := strings.Cut(condition, "=")
+
+			actual, ok := meta.Metadata[strings.ToLower(key)]
+			if !ok || withValue && actual != value {
+				return false
+			}
+		}
+
+		return true
+	}
+}
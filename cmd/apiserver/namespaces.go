@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+
+	"github.com/gorilla/mux"
+)
+
+// requestNamespace returns namespace of /ns/{namespace}/... endpoints, other endpoints use the default namespace.
+func requestNamespace(r *http.Request) string {
+	if namespace, ok := mux.Vars(r)["namespace"]; ok {
+		return namespace
+	}
+
+	return fragment.DefaultNamespace
+}
+
+func namespaceInfo(ns fragment.NamespaceUsage) *bucket.NamespaceInfo {
+	info := &bucket.NamespaceInfo{
+		Name:        ns.Name,
+		Replication: uint32(ns.Replication),
+		Quota:       ns.Quota,
+		CreatedAt:   ns.CreatedAt.Format(time.RFC3339),
+		Files:       uint32(ns.FileCount),
+		Size:        ns.Size,
+	}
+
+	if ns.Retention > 0 {
+		info.Retention = ns.Retention.String()
+	}
+
+	return info
+}
+
+func (s *ApiServer) listNamespaces(w http.ResponseWriter, r *http.Request) {
+	list := &bucket.NamespaceList{}
+	for _, ns := range s.fragmentRegistry.ListNamespaces() {
+		list.Namespaces = append(list.Namespaces, namespaceInfo(ns))
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *ApiServer) getNamespace(w http.ResponseWriter, r *http.Request) {
+	ns, err := s.fragmentRegistry.Namespace(mux.Vars(r)["namespace"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, namespaceInfo(ns))
+}
+
+// putNamespace creates the namespace or replaces its settings with NamespaceInfo from request body.
+// Replication is 1, when it is not set.
+func (s *ApiServer) putNamespace(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["namespace"]
+
+	var info bucket.NamespaceInfo
+	err := json.NewDecoder(r.Body).Decode(&info)
+	if err != nil {
+		writeError(w, newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, fmt.Errorf("invalid namespace settings: %w", err)))
+		return
+	}
+
+	settings := fragment.NamespaceSettings{
+		Replication: max(int(info.GetReplication()), 1),
+		Quota:       info.GetQuota(),
+	}
+
+	if s.dedup && settings.Replication > 1 {
+		writeError(w, errDedupReplication)
+		return
+	}
+
+	if len(info.GetRetention()) > 0 {
+		settings.Retention, err = time.ParseDuration(info.GetRetention())
+		if err != nil {
+			writeError(w, newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, fmt.Errorf("invalid retention: %w", err)))
+			return
+		}
+	}
+
+	created, err := s.fragmentRegistry.PutNamespace(name, settings)
+	if err != nil {
+		log.WithError(err).WithField("namespace", name).Error("failed to update namespace")
+		writeError(w, err)
+		return
+	}
+
+	ns, err := s.fragmentRegistry.Namespace(name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		log.WithField("namespace", name).Info("namespace is created")
+	}
+
+	writeJSON(w, status, namespaceInfo(ns))
+}
+
+// deleteNamespace removes the namespace, it must have no files.
+func (s *ApiServer) deleteNamespace(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["namespace"]
+
+	err := s.fragmentRegistry.DeleteNamespace(name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	log.WithField("namespace", name).Info("namespace is deleted")
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+)
+
+func putNamespace(t *testing.T, s *ApiServer, name, settings string, wantStatus int) *bucket.NamespaceInfo {
+	t.Helper()
+
+	w := serve(s, httptest.NewRequest(http.MethodPut, "/ns/"+name, strings.NewReader(settings)))
+	if w.Code != wantStatus {
+		t.Fatalf("PUT /ns/%s status = %d, want %d", name, w.Code, wantStatus)
+	}
+
+	var info bucket.NamespaceInfo
+	if w.Code < http.StatusBadRequest {
+		err := json.NewDecoder(w.Body).Decode(&info)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &info
+}
+
+func TestNamespaceQuota(t *testing.T) {
+	s, _ := newTestServer(t, serverNumber)
+
+	info := putNamespace(t, s, "small", `{"replication": 2, "quota": 5000}`, http.StatusCreated)
+	if info.Replication != 2 || info.Quota != 5000 {
+		t.Errorf("namespace replication = %d, quota = %d, want 2 and 5000", info.Replication, info.Quota)
+	}
+
+	data := testData(3000)
+	w := serve(s, httptest.NewRequest(http.MethodPut, "/ns/small/objects/a", bytes.NewReader(data)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("PUT object within quota status = %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	fm, ok := s.fragmentRegistry.Stat("small", "a")
+	if !ok {
+		t.Fatal("file is not committed to the namespace")
+	}
+	for _, f := range fm.Fragments {
+		if len(f.Replicas) != 2 {
+			t.Errorf("fragment %d has %d replicas, want 2", f.Index, len(f.Replicas))
+		}
+	}
+
+	if _, ok := s.fragmentRegistry.Stat(fragment.DefaultNamespace, "a"); ok {
+		t.Error("file is committed to default namespace")
+	}
+
+	w = serve(s, httptest.NewRequest(http.MethodGet, "/ns/small/objects/a", nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), data) {
+		t.Errorf("GET object status = %d, %d bytes, want %d and the uploaded data", w.Code, w.Body.Len(), http.StatusOK)
+	}
+
+	w = serve(s, httptest.NewRequest(http.MethodPut, "/ns/small/objects/b", bytes.NewReader(data)))
+	if w.Code != http.StatusInsufficientStorage {
+		t.Errorf("PUT object over quota status = %d, want %d", w.Code, http.StatusInsufficientStorage)
+	}
+
+	var wsErr bucket.WsError
+	err := json.NewDecoder(w.Body).Decode(&wsErr)
+	if err != nil || wsErr.Code != bucket.WsErrorCode_WS_ERROR_QUOTA_EXCEEDED {
+		t.Errorf("error code = %v, %v, want %v", wsErr.Code, err, bucket.WsErrorCode_WS_ERROR_QUOTA_EXCEEDED)
+	}
+
+	// the same object fits default namespace
+	w = serve(s, httptest.NewRequest(http.MethodPut, "/objects/b", bytes.NewReader(data)))
+	if w.Code != http.StatusCreated {
+		t.Errorf("PUT object to default namespace status = %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	// raised quota applies to existing namespace
+	info = putNamespace(t, s, "small", `{"replication": 2, "quota": 6000}`, http.StatusOK)
+	if info.Files != 1 || info.Size != 3000 {
+		t.Errorf("namespace has %d files of %d bytes, want 1 file of 3000 bytes", info.Files, info.Size)
+	}
+
+	w = serve(s, httptest.NewRequest(http.MethodPut, "/ns/small/objects/b", bytes.NewReader(data)))
+	if w.Code != http.StatusCreated {
+		t.Errorf("PUT object within raised quota status = %d, want %d", w.Code, http.StatusCreated)
+	}
+}
+
+func TestNamespaceErrors(t *testing.T) {
+	s, _ := newTestServer(t, 1)
+
+	putNamespace(t, s, "logs", `{"quota": -1}`, http.StatusBadRequest)
+	putNamespace(t, s, "logs", `{"retention": "week"}`, http.StatusBadRequest)
+	putNamespace(t, s, "Logs", `{}`, http.StatusBadRequest)
+
+	info := putNamespace(t, s, "logs", `{"retention": "1h"}`, http.StatusCreated)
+	if info.Replication != 1 || info.Retention != "1h0m0s" {
+		t.Errorf("namespace replication = %d, retention = %q, want 1 and 1h0m0s", info.Replication, info.Retention)
+	}
+
+	w := serve(s, httptest.NewRequest(http.MethodPut, "/ns/unknown/objects/a", bytes.NewReader(testData(100))))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("PUT object to unknown namespace status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w = serve(s, httptest.NewRequest(http.MethodPut, "/ns/logs/objects/a", bytes.NewReader(testData(100))))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("PUT object status = %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	tests := []struct {
+		name       string
+		namespace  string
+		wantStatus int
+	}{
+		{name: "default namespace", namespace: fragment.DefaultNamespace, wantStatus: http.StatusBadRequest},
+		{name: "unknown namespace", namespace: "unknown", wantStatus: http.StatusNotFound},
+		{name: "namespace with files", namespace: "logs", wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(s, httptest.NewRequest(http.MethodDelete, "/ns/"+tt.namespace, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("DELETE /ns/%s status = %d, want %d", tt.namespace, w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
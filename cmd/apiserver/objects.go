@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aburluka/k8test/internal/compression"
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+
+	"github.com/gorilla/mux"
+)
+
+// parseRange parses a single byte range of the Range header into offset and length.
+// Multiple ranges are not supported, they are reported as not satisfiable.
+func parseRange(header string, size int64) (int64, int64, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, fragment.ErrInvalidRange
+	}
+
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, fragment.ErrInvalidRange
+	}
+
+	// suffix range: last N bytes
+	if len(first) == 0 {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, fragment.ErrInvalidRange
+		}
+
+		n = min(n, size)
+		return size - n, n, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, fragment.ErrInvalidRange
+	}
+
+	end := size - 1
+	if len(last) > 0 {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, fragment.ErrInvalidRange
+		}
+
+		end = min(end, size-1)
+	}
+
+	return start, end - start + 1, nil
+}
+
+// putObject stores request body as a file, request must have Content-Length.
+// "If-None-Match: *" header prevents replacing existing file, expected checksum could be sent in X-File-Checksum header
+// and compression codec in X-File-Codec header. User metadata is sent in X-File-Meta-{key} headers.
+func (s *ApiServer) putObject(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	filename := vars["filename"]
+	if len(filename) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if r.ContentLength < 0 {
+		w.WriteHeader(http.StatusLengthRequired)
+		return
+	}
+
+	codec, err := compression.Parse(r.Header.Get(codecHeader), s.codec)
+	if err != nil {
+		writeError(w, newAPIError(bucket.WsErrorCode_WS_ERROR_BAD_REQUEST, err))
+		return
+	}
+
+	metadata, err := requestMetadata(r.Header)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	opts := fragment.UploadOptions{
+		Exclusive:   r.Header.Get("If-None-Match") == "*",
+		Checksum:    r.Header.Get(checksumHeader),
+		ContentType: r.Header.Get("Content-Type"),
+		Metadata:    metadata,
+		Codec:       codec,
+	}
+
+	session, err := s.beginUpload(requestNamespace(r), filename, r.ContentLength, opts)
+	if err != nil {
+		log.WithError(err).WithField("filename", filename).Error("failed to stage upload")
+		writeError(w, err)
+		return
+	}
+
+	_, err = s.store(r.Context(), session, false, newStreamChunkReader(r.Body))
+	if err != nil {
+		log.WithError(err).WithField("filename", filename).Error("failed to upload object")
+		writeError(w, err)
+		return
+	}
+
+	meta, ok := s.fragmentRegistry.Stat(requestNamespace(r), filename)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, fileStat(meta))
+}
+
+// getObject streams the file as response body.
+func (s *ApiServer) getObject(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	filename := vars["filename"]
+	if len(filename) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	meta, ok := s.fragmentRegistry.Acquire(requestNamespace(r), filename)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	defer s.fragmentRegistry.Release(meta)
+
+	w.Header().Set("Content-Type", contentType(meta))
+	setMetadataHeaders(w.Header(), meta)
+	if len(meta.Checksum) > 0 {
+		w.Header().Set("ETag", strconv.Quote(meta.Checksum))
+	}
+
+	status := http.StatusOK
+	ranges := meta.AllRanges()
+
+	// size is not known for files uploaded before it was recorded
+	if meta.Size > 0 {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", strconv.FormatInt(meta.Size, 10))
+	}
+
+	if header := r.Header.Get("Range"); len(header) > 0 && meta.Size > 0 {
+		var requested []fragment.FragmentRange
+
+		offset, length, err := parseRange(header, meta.Size)
+		if err == nil {
+			requested, err = meta.Ranges(offset, length)
+		}
+
+		// files without fragment sizes are served as a whole, ignoring the range
+		if err != nil && !errors.Is(err, fragment.ErrNoFragmentSizes) {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", meta.Size))
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+
+		if err == nil {
+			ranges = requested
+			status = http.StatusPartialContent
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, meta.Size))
+			w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+		}
+	}
+
+	w.WriteHeader(status)
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	// headers are already sent, so failure can only be reported by closing the connection
+	err := s.loadFile(r.Context(), meta, ranges, &streamChunkWriter{w: w})
+	if err != nil {
+		log.WithError(err).WithField("filename", filename).Error("failed to download object")
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/aburluka/k8test/internal/compression"
+	"github.com/aburluka/k8test/internal/fragment"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantOffset int64
+		wantLength int64
+		wantErr    error
+	}{
+		{name: "first bytes", header: "bytes=0-9", wantOffset: 0, wantLength: 10},
+		{name: "open range", header: "bytes=90-", wantOffset: 90, wantLength: 10},
+		{name: "end past size", header: "bytes=95-200", wantOffset: 95, wantLength: 5},
+		{name: "last byte", header: "bytes=99-99", wantOffset: 99, wantLength: 1},
+		{name: "suffix range", header: "bytes=-10", wantOffset: 90, wantLength: 10},
+		{name: "suffix longer than file", header: "bytes=-500", wantOffset: 0, wantLength: 100},
+		{name: "empty suffix", header: "bytes=-0", wantErr: fragment.ErrInvalidRange},
+		{name: "start at size", header: "bytes=100-", wantErr: fragment.ErrInvalidRange},
+		{name: "start past size", header: "bytes=200-300", wantErr: fragment.ErrInvalidRange},
+		{name: "end before start", header: "bytes=10-5", wantErr: fragment.ErrInvalidRange},
+		{name: "multiple ranges", header: "bytes=0-9,20-29", wantErr: fragment.ErrInvalidRange},
+		{name: "another unit", header: "items=0-9", wantErr: fragment.ErrInvalidRange},
+		{name: "no dash", header: "bytes=10", wantErr: fragment.ErrInvalidRange},
+		{name: "negative start", header: "bytes=-5-10", wantErr: fragment.ErrInvalidRange},
+		{name: "not a number", header: "bytes=a-b", wantErr: fragment.ErrInvalidRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, length, err := parseRange(tt.header, 100)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseRange() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if offset != tt.wantOffset || length != tt.wantLength {
+				t.Errorf("parseRange() = %d, %d, want %d, %d", offset, length, tt.wantOffset, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestGetObjectRange(t *testing.T) {
+	s, _ := newTestServer(t, 3)
+
+	data := testData(10000)
+	putTestObject(t, s, "a", data)
+
+	tests := []struct {
+		name             string
+		header           string
+		wantStatus       int
+		wantContentRange string
+		wantBody         []byte
+	}{
+		{name: "whole object", wantStatus: http.StatusOK, wantBody: data},
+		{name: "range inside fragment", header: "bytes=10-19", wantStatus: http.StatusPartialContent, wantContentRange: "bytes 10-19/10000", wantBody: data[10:20]},
+		{name: "range across fragments", header: "bytes=1000-8999", wantStatus: http.StatusPartialContent, wantContentRange: "bytes 1000-8999/10000", wantBody: data[1000:9000]},
+		{name: "suffix range", header: "bytes=-100", wantStatus: http.StatusPartialContent, wantContentRange: "bytes 9900-9999/10000", wantBody: data[9900:]},
+		{name: "range past end", header: "bytes=10000-", wantStatus: http.StatusRequestedRangeNotSatisfiable, wantContentRange: "bytes */10000"},
+		{name: "empty suffix range", header: "bytes=-0", wantStatus: http.StatusRequestedRangeNotSatisfiable, wantContentRange: "bytes */10000"},
+		{name: "multiple ranges", header: "bytes=0-1,5-6", wantStatus: http.StatusRequestedRangeNotSatisfiable, wantContentRange: "bytes */10000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/objects/a", nil)
+			if len(tt.header) > 0 {
+				r.Header.Set("Range", tt.header)
+			}
+
+			w := serve(s, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if got := w.Header().Get("Content-Range"); got != tt.wantContentRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.wantContentRange)
+			}
+
+			if tt.wantBody != nil && !bytes.Equal(w.Body.Bytes(), tt.wantBody) {
+				t.Errorf("got %d bytes, which differ from %d bytes of the range", w.Body.Len(), len(tt.wantBody))
+			}
+		})
+	}
+}
+
+func TestGetObjectLegacy(t *testing.T) {
+	s, buckets := newTestServer(t, 1)
+
+	data := testData(3000)
+	buckets[0].put("a", 0, data[:1000])
+	buckets[0].put("a", 1, data[1000:])
+	buckets[0].put("b", 0, data)
+
+	// files uploaded before sizes were recorded
+	files := fmt.Sprintf(`{"files": {
+		"a": {"status": %d, "filename": "a", "size": 3000, "addresses": [%q, %q]},
+		"b": {"status": %[1]d, "filename": "b", "addresses": [%[2]q]}
+	}}`, fragment.UploadStatusComplete, buckets[0].address, buckets[0].address)
+
+	err := os.WriteFile("fragments.json", []byte(files), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.fragmentRegistry, err = fragment.NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	for _, filename := range []string{"a", "b"} {
+		t.Run(filename, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/objects/"+filename, nil)
+			r.Header.Set("Range", "bytes=10-19")
+
+			// the range can't be mapped to fragments, so the whole file is served
+			w := serve(s, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+
+			if !bytes.Equal(w.Body.Bytes(), data) {
+				t.Errorf("got %d bytes, which differ from %d bytes of the file", w.Body.Len(), len(data))
+			}
+		})
+	}
+}
+
+func TestCompressedObject(t *testing.T) {
+	data := bytes.Repeat([]byte("compressible text "), 1000)
+
+	for _, codec := range []string{compression.None, compression.Gzip, compression.Zstd} {
+		t.Run(codec, func(t *testing.T) {
+			s, buckets := newTestServer(t, 3)
+
+			r := httptest.NewRequest(http.MethodPut, "/objects/a", bytes.NewReader(data))
+			r.Header.Set(codecHeader, codec)
+
+			w := serve(s, r)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("PUT status = %d, want %d", w.Code, http.StatusCreated)
+			}
+
+			var stored int
+			for _, b := range buckets {
+				stored += b.storedBytes()
+			}
+
+			if compressed := stored < len(data)/2; compressed != compression.Compressed(codec) {
+				t.Errorf("%d bytes are stored as %d bytes with %s codec", len(data), stored, codec)
+			}
+
+			// ranges are offsets in original data, even when fragments are compressed
+			for _, rng := range [][2]int{{0, len(data) - 1}, {10, 19}, {5000, 15999}, {len(data) - 1, len(data) - 1}} {
+				r := httptest.NewRequest(http.MethodGet, "/objects/a", nil)
+				r.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", rng[0], rng[1]))
+
+				w := serve(s, r)
+				if w.Code != http.StatusPartialContent || !bytes.Equal(w.Body.Bytes(), data[rng[0]:rng[1]+1]) {
+					t.Errorf("range %d-%d: got %d bytes with %d status, which differ from the original range", rng[0], rng[1], w.Body.Len(), w.Code)
+				}
+			}
+		})
+	}
+
+	t.Run("unknown codec", func(t *testing.T) {
+		s, _ := newTestServer(t, 3)
+
+		r := httptest.NewRequest(http.MethodPut, "/objects/a", bytes.NewReader(data))
+		r.Header.Set(codecHeader, "lz4")
+
+		w := serve(s, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("PUT status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
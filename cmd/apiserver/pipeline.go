@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	bucket "github.com/aburluka/k8test/internal/proto"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	fragmentQueueChunks = 8
+
+	// bucket servers tried to store a fragment, besides its replicas
+	fragmentUploadAttempts = 3
+)
+
+type (
+	fragmentUpload struct {
+		fragment  int
+		addresses []string // bucket servers, which the fragment is sent to
+		replicas  []string // bucket servers, which stored the fragment
+
+		size      int64
+		checksum  string
+		hashState []byte
+
+		queue  chan []byte
+		queued bool
+		done   chan struct{}
+		err    error
+	}
+
+	fragmentSender struct {
+		s        *ApiServer
+		ctx      context.Context
+		upload   *fragmentUpload
+		uploadID string
+		codec    string
+		dataKey  []byte
+		spare    []string
+
+		// chunks sent so far are kept for a spare bucket server, fragments are at most maxFragmentSize
+		sent [][]byte
+	}
+
+	replicaStream struct {
+		address string
+		stream  bucket.BucketService_UploadChunksClient
+		sink    *fragmentWriter
+		cancel  context.CancelFunc
+	}
+)
+
+func (s *ApiServer) startFragmentUpload(ctx context.Context, uploadID string, fragmentNumber int, addresses []string, replicas int, codec string, dataKey []byte) *fragmentUpload {
+	addresses = addresses[:min(len(addresses), replicas+fragmentUploadAttempts-1)]
+
+	u := &fragmentUpload{
+		fragment: fragmentNumber,
+		queue:    make(chan []byte, fragmentQueueChunks),
+		done:     make(chan struct{}),
+	}
+
+	f := &fragmentSender{
+		s:        s,
+		ctx:      ctx,
+		upload:   u,
+		uploadID: uploadID,
+		codec:    codec,
+		dataKey:  dataKey,
+		spare:    addresses[replicas:],
+	}
+
+	go func() {
+		defer close(u.done)
+
+		u.err = f.send(addresses[:replicas])
+	}()
+
+	return u
+}
+
+func (f *fragmentSender) send(addresses []string) error {
+	streams := make([]*replicaStream, 0, len(addresses))
+	defer func() {
+		for _, r := range streams {
+			r.cancel()
+		}
+	}()
+
+	for _, address := range addresses {
+		r, err := f.open(address)
+		if err != nil {
+			r, err = f.replace(address, err)
+			if err != nil {
+				return err
+			}
+		}
+
+		streams = append(streams, r)
+	}
+
+	for b := range f.upload.queue {
+		f.sent = append(f.sent, b)
+
+		for i, r := range streams {
+			_, err := r.sink.Write(b)
+			if err != nil {
+				// the chunk is replayed to the replacement with the rest of sent data
+				r, err = f.replace(r.address, r.fail(err))
+				if err != nil {
+					return err
+				}
+
+				streams[i] = r
+			}
+		}
+	}
+
+	for _, r := range streams {
+		err := f.close(r)
+		for err != nil {
+			r, err = f.replace(r.address, err)
+			if err != nil {
+				return err
+			}
+
+			err = f.close(r)
+		}
+
+		f.upload.replicas = append(f.upload.replicas, r.address)
+	}
+
+	return nil
+}
+
+func (f *fragmentSender) replace(failed string, cause error) (*replicaStream, error) {
+	for {
+		if !failover(f.ctx, cause) || len(f.spare) == 0 {
+			return nil, cause
+		}
+
+		address := f.spare[0]
+		f.spare = f.spare[1:]
+
+		log.WithError(cause).WithField("key", f.uploadID).Warnf("failed to store %d fragment on %s, retrying on %s", f.upload.fragment, failed, address)
+
+		r, err := f.open(address)
+		if err == nil {
+			for _, b := range f.sent {
+				_, err = r.sink.Write(b)
+				if err != nil {
+					err = r.fail(err)
+					break
+				}
+			}
+		}
+
+		if err == nil {
+			return r, nil
+		}
+
+		failed, cause = address, err
+	}
+}
+
+func (f *fragmentSender) open(address string) (*replicaStream, error) {
+	f.upload.addresses = append(f.upload.addresses, address)
+
+	grpcClient, err := f.s.bucketClient(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init bucket server %s GRPC client: %w", address, err)
+	}
+
+	ctx, cancel := context.WithCancel(f.ctx)
+
+	stream, err := grpcClient.UploadChunks(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to upload chunks to %s: %w", address, err)
+	}
+
+	sink, err := newFragmentWriter(stream, f.uploadID, f.upload.fragment, f.codec, f.dataKey)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &replicaStream{
+		address: address,
+		stream:  stream,
+		sink:    sink,
+		cancel:  cancel,
+	}, nil
+}
+
+func (f *fragmentSender) close(r *replicaStream) error {
+	err := r.sink.Close()
+	if err != nil {
+		return r.fail(err)
+	}
+	defer r.cancel()
+
+	response, err := r.stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("failed to store fragment on %s: %w", r.address, err)
+	}
+
+	return f.s.confirmFragment(r.sink, r.address, response)
+}
+
+func (r *replicaStream) fail(err error) error {
+	defer r.cancel()
+
+	// stream is broken by bucket server, its status is received here
+	if errors.Is(err, io.EOF) {
+		_, recvErr := r.stream.CloseAndRecv()
+		if recvErr != nil {
+			return fmt.Errorf("failed to store fragment on %s: %w", r.address, recvErr)
+		}
+	}
+
+	return fmt.Errorf("failed to send chunk to %s: %w", r.address, err)
+}
+
+func failover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.AlreadyExists, codes.InvalidArgument, codes.Canceled:
+		return false
+	}
+
+	return true
+}
+
+func (u *fragmentUpload) write(b []byte) error {
+	select {
+	case u.queue <- b:
+		return nil
+	case <-u.done:
+		if u.err != nil {
+			return u.err
+		}
+
+		return fmt.Errorf("%d fragment upload is finished", u.fragment)
+	}
+}
+
+func (u *fragmentUpload) finish(size int64, checksum string, hashState []byte) {
+	u.size, u.checksum, u.hashState = size, checksum, hashState
+	u.queued = true
+	close(u.queue)
+}
+
+func (u *fragmentUpload) finished() bool {
+	select {
+	case <-u.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (u *fragmentUpload) wait() error {
+	<-u.done
+	return u.err
+}
+
+func (s *ApiServer) abortFragments(cancel context.CancelFunc, uploadID string, uploads []*fragmentUpload) {
+	cancel()
+
+	for _, u := range uploads {
+		if !u.queued {
+			close(u.queue)
+		}
+		u.wait()
+
+		for _, address := range u.addresses {
+			err := s.deleteFragmentWithRetries(uploadID, address, u.fragment)
+			if err != nil {
+				log.WithError(err).WithFields(logrus.Fields{"key": uploadID, "address": address}).Errorf("failed to remove aborted %d fragment", u.fragment)
+			}
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aburluka/k8test/internal/fragment"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type commitRecorder struct {
+	*streamChunkReader
+	committed []int64
+}
+
+func (r *commitRecorder) Committed(offset int64) error {
+	r.committed = append(r.committed, offset)
+	return nil
+}
+
+func setOnUpload(buckets []*testBucket, f func(ctx context.Context, filename string, fragment uint32) error) {
+	for _, b := range buckets {
+		b.setOnUpload(f)
+	}
+}
+
+// storeTestFile uploads data as a resumable upload, the upload session is returned.
+func storeTestFile(t *testing.T, s *ApiServer, data []byte) (*commitRecorder, *fragment.FileMeta, error) {
+	t.Helper()
+
+	session, err := s.fragmentRegistry.BeginUpload(fragment.DefaultNamespace, "a", int64(len(data)), fragment.UploadOptions{})
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+
+	source := &commitRecorder{streamChunkReader: newStreamChunkReader(bytes.NewReader(data))}
+	_, err = s.storeFile(context.Background(), session, true, source)
+
+	return source, session, err
+}
+
+func TestUploadParallelism(t *testing.T) {
+	for _, parallelism := range []int{1, 3, serverNumber} {
+		s, buckets := newTestServer(t, serverNumber)
+		s.uploadParallelism = parallelism
+
+		var (
+			lock              sync.Mutex
+			inflight, maximum int
+		)
+		setOnUpload(buckets, func(ctx context.Context, filename string, fragment uint32) error {
+			lock.Lock()
+			inflight++
+			maximum = max(maximum, inflight)
+			lock.Unlock()
+
+			time.Sleep(50 * time.Millisecond)
+
+			lock.Lock()
+			inflight--
+			lock.Unlock()
+
+			return nil
+		})
+
+		_, _, err := storeTestFile(t, s, testData(60000))
+		if err != nil {
+			t.Fatalf("storeFile() error = %v", err)
+		}
+
+		if maximum > parallelism || (parallelism > 1 && maximum < 2) {
+			t.Errorf("%d fragments are sent at once with %d parallelism", maximum, parallelism)
+		}
+	}
+}
+
+func TestUploadCommitOrder(t *testing.T) {
+	s, buckets := newTestServer(t, serverNumber)
+	s.uploadParallelism = serverNumber
+
+	// earlier fragments are stored later
+	setOnUpload(buckets, func(ctx context.Context, filename string, fragment uint32) error {
+		time.Sleep(time.Duration(serverNumber-int(fragment)) * 10 * time.Millisecond)
+		return nil
+	})
+
+	data := testData(60000)
+	source, _, err := storeTestFile(t, s, data)
+	if err != nil {
+		t.Fatalf("storeFile() error = %v", err)
+	}
+
+	meta, ok := s.fragmentRegistry.Stat(fragment.DefaultNamespace, "a")
+	if !ok {
+		t.Fatal("uploaded file is not found")
+	}
+
+	// fragments are committed one by one in the file order
+	var want []int64
+	var offset int64
+	for _, f := range meta.Fragments {
+		offset += f.Length
+		want = append(want, offset)
+	}
+
+	if !slices.Equal(source.committed, want) || offset != int64(len(data)) {
+		t.Errorf("committed offsets = %v, want %v", source.committed, want)
+	}
+}
+
+func TestUploadAbortFragments(t *testing.T) {
+	s, buckets := newTestServer(t, serverNumber)
+	s.uploadParallelism = 4
+
+	// fragments after the failed one are stored, before the failure is noticed
+	errFailed := errors.New("failed to store fragment")
+	var stored atomic.Int32
+	setOnUpload(buckets, func(ctx context.Context, filename string, fragment uint32) error {
+		switch fragment {
+		case 0, 2, 3:
+			stored.Add(1)
+			return nil
+		case 1:
+			for stored.Load() < 3 {
+				time.Sleep(time.Millisecond)
+			}
+			return errFailed
+		}
+
+		return nil
+	})
+
+	_, session, err := storeTestFile(t, s, testData(60000))
+	if err == nil {
+		t.Fatal("storeFile() with failed fragment succeeded")
+	}
+
+	resumed, err := s.fragmentRegistry.ResumeUpload(session.UploadID, fragment.DefaultNamespace, "a", session.Size)
+	if err != nil {
+		t.Fatalf("ResumeUpload() error = %v", err)
+	}
+
+	if len(resumed.Fragments) != 1 {
+		t.Fatalf("%d fragments are registered, want only the one before the failed fragment", len(resumed.Fragments))
+	}
+
+	// only the registered fragment is kept, so resumed upload stores the others again
+	var kept []uint32
+	for _, b := range buckets {
+		kept = append(kept, b.storedFragments(session.UploadID)...)
+	}
+
+	if !slices.Equal(kept, []uint32{0}) {
+		t.Errorf("fragments %v are kept on bucket servers, want only the registered one", kept)
+	}
+}
+
+func TestUploadStoredMismatch(t *testing.T) {
+	t.Run("fragment is sent to the next bucket", func(t *testing.T) {
+		s, buckets := newTestServer(t, 3)
+		buckets[0].corrupt = true
+
+		data := testData(60000)
+		_, session, err := storeTestFile(t, s, data)
+		if err != nil {
+			t.Fatalf("storeFile() error = %v", err)
+		}
+
+		// mismatched fragments are removed from the corrupt bucket
+		if stored := buckets[0].storedFragments(session.UploadID); len(stored) > 0 {
+			t.Errorf("mismatched fragments %v are kept", stored)
+		}
+
+		meta, ok := s.fragmentRegistry.Stat(fragment.DefaultNamespace, "a")
+		if !ok {
+			t.Fatal("uploaded file is not found")
+		}
+
+		for _, f := range meta.Fragments {
+			if slices.Contains(f.Replicas, buckets[0].address) {
+				t.Errorf("corrupt bucket is registered in %d fragment replicas %v", f.Index, f.Replicas)
+			}
+		}
+
+		w := serve(s, httptest.NewRequest(http.MethodGet, "/objects/a", nil))
+		if !bytes.Equal(w.Body.Bytes(), data) {
+			t.Errorf("downloaded %d bytes, which differ from %d uploaded bytes", w.Body.Len(), len(data))
+		}
+	})
+
+	t.Run("all buckets are corrupt", func(t *testing.T) {
+		s, buckets := newTestServer(t, 2)
+		for _, b := range buckets {
+			b.corrupt = true
+		}
+
+		_, _, err := storeTestFile(t, s, testData(60000))
+		if !errors.Is(err, errStoredMismatch) {
+			t.Errorf("storeFile() error = %v, want %v", err, errStoredMismatch)
+		}
+	})
+}
+
+func TestUploadFailover(t *testing.T) {
+	s, buckets := newTestServer(t, 3)
+	// the file is a single fragment of the largest size
+	s.fragmentPlanner.Fragments = 1
+
+	// the first bucket, which receives a half of the fragment, goes down
+	var failed atomic.Value
+	for _, b := range buckets {
+		b.setOnChunk(func(fragment uint32, received int) error {
+			if received >= maxFragmentSize/2 && failed.CompareAndSwap(nil, b.address) {
+				return status.Error(codes.Unavailable, "bucket server is stopped")
+			}
+
+			return nil
+		})
+	}
+
+	data := testData(maxFragmentSize)
+	_, session, err := storeTestFile(t, s, data)
+	if err != nil {
+		t.Fatalf("storeFile() error = %v", err)
+	}
+
+	if failed.Load() == nil {
+		t.Fatal("no bucket failed in the middle of the fragment")
+	}
+
+	meta, ok := s.fragmentRegistry.Stat(fragment.DefaultNamespace, "a")
+	if !ok {
+		t.Fatal("uploaded file is not found")
+	}
+
+	if len(meta.Fragments) != 1 || len(meta.Fragments[0].Replicas) != 1 {
+		t.Fatalf("fragments = %+v, want a single fragment with a single replica", meta.Fragments)
+	}
+
+	spare := meta.Fragments[0].Replicas[0]
+	if spare == failed.Load() {
+		t.Fatalf("failed bucket %s is registered as the replica", spare)
+	}
+
+	// the spare bucket got the whole fragment, sent before the failure and after it
+	for _, b := range buckets {
+		if b.address != spare {
+			continue
+		}
+
+		stored, ok := b.get(session.UploadID, 0)
+		if !ok || !bytes.Equal(stored, data) {
+			t.Errorf("spare bucket stored %d bytes, which differ from %d bytes of the fragment", len(stored), len(data))
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aburluka/k8test/internal/fragment"
+)
+
+const (
+	// the range being sent is buffered outside of the memory limit, so it never waits for prefetched ones
+	headBufferChunks = 4
+)
+
+type (
+	prefetchChunk struct {
+		data      []byte
+		accounted bool // counted in the memory limit
+	}
+
+	prefetchRange struct {
+		chunks   []prefetchChunk
+		buffered int
+		done     bool
+		err      error
+	}
+
+	// prefetcher downloads up to parallelism fragment ranges at once and keeps them in memory
+	// until they are sent in order. Buffered data of ranges following the sent one is limited by memory.
+	prefetcher struct {
+		lock sync.Mutex
+		cond *sync.Cond
+
+		ranges      []*prefetchRange
+		head        int
+		available   int64
+		parallelism int
+		stopped     bool
+	}
+
+	prefetchWriter struct {
+		p     *prefetcher
+		index int
+	}
+)
+
+func newPrefetcher(ranges int, parallelism int, memory int64) *prefetcher {
+	p := &prefetcher{
+		ranges:      make([]*prefetchRange, ranges),
+		available:   memory,
+		parallelism: parallelism,
+	}
+	p.cond = sync.NewCond(&p.lock)
+
+	for i := range p.ranges {
+		p.ranges[i] = &prefetchRange{}
+	}
+
+	return p
+}
+
+// loadFileParallel downloads fragment ranges concurrently and writes them to the destination in order.
+func (s *ApiServer) loadFileParallel(ctx context.Context, ranges []fragment.FragmentRange, destination chunkWriter) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := newPrefetcher(len(ranges), s.downloadParallelism, s.downloadMemory)
+	defer p.stop()
+
+	go func() {
+		for i, fr := range ranges {
+			if !p.waitTurn(i) {
+				return
+			}
+
+			go func() {
+				err := s.fetchRange(ctx, fr, &prefetchWriter{p: p, index: i})
+				p.finish(i, err)
+			}()
+		}
+	}()
+
+	for {
+		data, accounted, err := p.next()
+		if err != nil {
+			return err
+		}
+
+		if data == nil {
+			return nil
+		}
+
+		err = destination.WriteChunk(data)
+		if err != nil {
+			return fmt.Errorf("failed to sent chunk: %w", err)
+		}
+
+		p.release(len(data), accounted)
+	}
+}
+
+func (s *ApiServer) fetchRange(ctx context.Context, fr fragment.FragmentRange, destination chunkWriter) error {
+	fr.Address = s.replicaAddress(fr)
+
+	grpcClient, err := s.bucketClient(fr.Address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", fr.Address, err)
+	}
+
+	return s.loadRange(ctx, grpcClient, fr, destination)
+}
+
+// WriteChunk keeps a copy of the chunk, since range loader could reuse the buffer.
+func (w *prefetchWriter) WriteChunk(b []byte) error {
+	accounted, ok := w.p.acquire(w.index, len(b))
+	if !ok {
+		return context.Canceled
+	}
+
+	data := append([]byte(nil), b...)
+
+	w.p.lock.Lock()
+	defer w.p.lock.Unlock()
+
+	r := w.p.ranges[w.index]
+	r.chunks = append(r.chunks, prefetchChunk{data: data, accounted: accounted})
+	if !accounted {
+		r.buffered++
+	}
+	w.p.cond.Broadcast()
+
+	return nil
+}
+
+// waitTurn blocks until the range is within parallelism ranges from the one being sent.
+func (p *prefetcher) waitTurn(index int) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for !p.stopped && index >= p.head+p.parallelism {
+		p.cond.Wait()
+	}
+
+	return !p.stopped
+}
+
+// acquire waits for memory to buffer the chunk of the range, it reports whether the chunk is counted in the limit.
+func (p *prefetcher) acquire(index int, size int) (bool, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for !p.stopped {
+		if index == p.head && p.ranges[index].buffered < headBufferChunks {
+			return false, true
+		}
+
+		if index != p.head && p.available >= int64(size) {
+			p.available -= int64(size)
+			return true, true
+		}
+
+		p.cond.Wait()
+	}
+
+	return false, false
+}
+
+func (p *prefetcher) release(size int, accounted bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if accounted {
+		p.available += int64(size)
+	} else {
+		p.ranges[p.head].buffered--
+	}
+	p.cond.Broadcast()
+}
+
+func (p *prefetcher) finish(index int, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.ranges[index].done = true
+	p.ranges[index].err = err
+	p.cond.Broadcast()
+}
+
+// next returns the next chunk to send in order, nil data means that all ranges are sent.
+func (p *prefetcher) next() ([]byte, bool, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for p.head < len(p.ranges) {
+		r := p.ranges[p.head]
+		if len(r.chunks) > 0 {
+			chunk := r.chunks[0]
+			r.chunks[0] = prefetchChunk{}
+			r.chunks = r.chunks[1:]
+
+			return chunk.data, chunk.accounted, nil
+		}
+
+		if !r.done {
+			p.cond.Wait()
+			continue
+		}
+
+		if r.err != nil {
+			return nil, false, r.err
+		}
+
+		p.head++
+		p.cond.Broadcast()
+	}
+
+	return nil, false, nil
+}
+
+func (p *prefetcher) stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.stopped = true
+	p.cond.Broadcast()
+}
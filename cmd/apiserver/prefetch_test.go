@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aburluka/k8test/internal/fragment"
+	bucket "github.com/aburluka/k8test/internal/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testRanges uploads the file and returns ranges of its fragments.
+func testRanges(t *testing.T, s *ApiServer, data []byte) []fragment.FragmentRange {
+	t.Helper()
+
+	putTestObject(t, s, "a", data)
+
+	meta, ok := s.fragmentRegistry.Acquire(fragment.DefaultNamespace, "a")
+	if !ok {
+		t.Fatal("uploaded file is not found")
+	}
+	t.Cleanup(func() { s.fragmentRegistry.Release(meta) })
+
+	ranges, err := meta.Ranges(0, meta.Size)
+	if err != nil {
+		t.Fatalf("Ranges() error = %v", err)
+	}
+
+	if len(ranges) != serverNumber {
+		t.Fatalf("file is split to %d fragments, want %d", len(ranges), serverNumber)
+	}
+
+	return ranges
+}
+
+func setOnDownload(buckets []*testBucket, f func(ctx context.Context, r *bucket.DownloadRequest) error) {
+	for _, b := range buckets {
+		b.setOnDownload(f)
+	}
+}
+
+func TestPrefetchOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		parallelism int
+		memory      int64
+	}{
+		{name: "sequential download", parallelism: 1, memory: 0},
+		{name: "without memory for prefetch", parallelism: 4, memory: 0},
+		{name: "memory for a single chunk", parallelism: 4, memory: 1500},
+		{name: "all fragments at once", parallelism: serverNumber, memory: 64 << 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, buckets := newTestServer(t, serverNumber)
+			s.downloadParallelism = tt.parallelism
+			s.downloadMemory = tt.memory
+
+			data := testData(60000)
+			putTestObject(t, s, "a", data)
+
+			// later fragments are ready first
+			setOnDownload(buckets, func(ctx context.Context, r *bucket.DownloadRequest) error {
+				time.Sleep(time.Duration(serverNumber-int(r.GetFragment())) * 5 * time.Millisecond)
+				return nil
+			})
+
+			w := serve(s, httptest.NewRequest(http.MethodGet, "/objects/a", nil))
+			if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), data) {
+				t.Errorf("downloaded %d bytes with %d status, which differ from %d uploaded bytes", w.Body.Len(), w.Code, len(data))
+			}
+		})
+	}
+}
+
+func TestPrefetchCancel(t *testing.T) {
+	s, buckets := newTestServer(t, serverNumber)
+	s.downloadParallelism = 4
+	s.downloadMemory = 64 << 20
+
+	ranges := testRanges(t, s, testData(60000))
+
+	// the first fragment is sent, while others hang until the download is canceled
+	var started, canceled atomic.Int32
+	setOnDownload(buckets, func(ctx context.Context, r *bucket.DownloadRequest) error {
+		if r.GetFragment() == 0 {
+			return nil
+		}
+
+		started.Add(1)
+		<-ctx.Done()
+		canceled.Add(1)
+
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the client disconnects after the first fragment
+	var sent int64
+	destination := chunkWriterFunc(func(b []byte) error {
+		sent += int64(len(b))
+		if sent == ranges[0].Length {
+			cancel()
+		}
+
+		return nil
+	})
+
+	err := s.loadFileParallel(ctx, ranges, destination)
+	if err == nil {
+		t.Fatal("loadFileParallel() of canceled download succeeded")
+	}
+
+	if sent != ranges[0].Length {
+		t.Errorf("%d bytes are sent, want %d bytes of the first fragment", sent, ranges[0].Length)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for canceled.Load() < started.Load() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if started.Load() == 0 || canceled.Load() != started.Load() {
+		t.Errorf("%d of %d prefetched fragments are canceled", canceled.Load(), started.Load())
+	}
+}
+
+func TestPrefetchLaterError(t *testing.T) {
+	s, buckets := newTestServer(t, serverNumber)
+	s.downloadParallelism = 4
+	s.downloadMemory = 64 << 20
+
+	data := testData(60000)
+	ranges := testRanges(t, s, data)
+
+	// the failed fragment is reported, when earlier ones are still downloaded
+	setOnDownload(buckets, func(ctx context.Context, r *bucket.DownloadRequest) error {
+		if r.GetFragment() == 2 {
+			return status.Error(codes.NotFound, "fragment is not found")
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+
+	var sent bytes.Buffer
+	err := s.loadFileParallel(context.Background(), ranges, &streamChunkWriter{w: &sent})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("loadFileParallel() error = %v, want error of the failed fragment", err)
+	}
+
+	// fragments before the failed one are sent in full
+	want := data[:ranges[0].Length+ranges[1].Length]
+	if !bytes.Equal(sent.Bytes(), want) {
+		t.Errorf("%d bytes are sent, want %d bytes of fragments before the failed one", sent.Len(), len(want))
+	}
+}
+
+type chunkWriterFunc func([]byte) error
+
+func (f chunkWriterFunc) WriteChunk(b []byte) error {
+	return f(b)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aburluka/k8test/internal/mtls"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	credentialsReloadInterval = 5 * time.Second
+)
+
+func (s *ApiServer) verifyBucket(ctx context.Context, address string) error {
+	if s.credentials == nil {
+		return nil
+	}
+
+	err := mtls.VerifyPeerAddress(ctx, address)
+	if err != nil {
+		log.WithError(err).WithField("server", address).Warn("bucket server certificate doesn't match its address")
+
+		return status.Errorf(codes.PermissionDenied, "certificate is not valid for %s: %v", address, err)
+	}
+
+	return nil
+}
+
+func (s *ApiServer) watchCredentials() {
+	for range time.Tick(credentialsReloadInterval) {
+		reloaded, err := s.credentials.Reload()
+		if err != nil {
+			log.WithError(err).Error("failed to reload TLS credentials, previous ones are kept")
+			continue
+		}
+
+		if reloaded {
+			log.Info("TLS credentials are reloaded")
+		}
+	}
+}
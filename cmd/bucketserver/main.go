@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
@@ -11,8 +13,11 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/aburluka/k8test/internal/fragment"
+	"github.com/aburluka/k8test/internal/mtls"
 	bucket "github.com/aburluka/k8test/internal/proto"
 
 	"github.com/sirupsen/logrus"
@@ -21,6 +26,7 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health"
 	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/status"
 )
 
@@ -29,6 +35,7 @@ type (
 		apiServerConn        *grpc.ClientConn
 		apiServiceGRPCClient bucket.ApiServiceClient
 		fragmentStorage      *fragment.Storage
+		credentials          *mtls.Credentials
 
 		bucket.UnimplementedBucketServiceServer
 	}
@@ -36,6 +43,13 @@ type (
 
 const (
 	readChunkSize = 2 << 16
+
+	// API server pings idle connections to notice failed bucket servers
+	keepaliveMinTime = 10 * time.Second
+
+	deregisterTimeout = 5 * time.Second
+
+	credentialsReloadInterval = 5 * time.Second
 )
 
 var (
@@ -43,12 +57,19 @@ var (
 	address           *string
 	apiServerAddress  *string
 	fragmentDirectory *string
+
+	tlsCert *string
+	tlsKey  *string
+	tlsCA   *string
 )
 
 func init() {
 	address = flag.String("address", "0.0.0.0:6571", "bucket server address")
 	apiServerAddress = flag.String("api-server", "0.0.0.0:6565", "API server address")
 	fragmentDirectory = flag.String("fragments", "fragments", "fragments storage directory")
+	tlsCert = flag.String("tls-cert", "", "certificate file for the host of bucket server address, API server is connected with mutual TLS when set")
+	tlsKey = flag.String("tls-key", "", "private key file of the certificate")
+	tlsCA = flag.String("tls-ca", "", "CA file, which certificates of API server are verified with")
 }
 
 func main() {
@@ -61,7 +82,7 @@ func main() {
 	defer s.shutdown()
 
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
 }
 
@@ -75,6 +96,15 @@ func NewBucketServer() (*BucketServer, error) {
 		return nil, err
 	}
 
+	if len(*tlsCert) > 0 || len(*tlsKey) > 0 || len(*tlsCA) > 0 {
+		s.credentials, err = mtls.Load(*tlsCert, *tlsKey, *tlsCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS credentials: %w", err)
+		}
+
+		go s.watchCredentials()
+	}
+
 	err = s.initGRPCClient()
 	if err != nil {
 		return nil, err
@@ -90,9 +120,9 @@ func NewBucketServer() (*BucketServer, error) {
 
 func (s *BucketServer) UploadChunks(stream bucket.BucketService_UploadChunksServer) error {
 	var (
-		b        bytes.Buffer
-		filename string
-		fragment int
+		b              bytes.Buffer
+		filename       string
+		fragmentNumber int
 	)
 
 	for {
@@ -108,18 +138,27 @@ func (s *BucketServer) UploadChunks(stream bucket.BucketService_UploadChunksServ
 
 		b.Write(request.Chunk.Data)
 		filename = request.GetFilename()
-		fragment = int(request.GetFragment())
+		fragmentNumber = int(request.GetFragment())
 	}
 
-	err := s.fragmentStorage.Put(filename, fragment, b.Bytes())
+	err := s.fragmentStorage.Put(filename, fragmentNumber, b.Bytes())
+	if errors.Is(err, fragment.ErrFragmentExists) {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
 	if err != nil {
 		log.WithError(err).Error("failed to put fragment")
 		return err
 	}
 	
-	log.WithFields(logrus.Fields{"filename": filename, "fragment": fragment}).Info("fragment stored")
+	log.WithFields(logrus.Fields{"filename": filename, "fragment": fragmentNumber}).Info("fragment stored")
 
-	return nil
+	// API server registers the fragment only when it matches the sent data
+	checksum := sha256.Sum256(b.Bytes())
+
+	return stream.SendAndClose(&bucket.UploadResponse{
+		Size:     int64(b.Len()),
+		Checksum: hex.EncodeToString(checksum[:]),
+	})
 }
 
 func (s *BucketServer) DownloadChunks(r *bucket.DownloadRequest, stream bucket.BucketService_DownloadChunksServer) error {
@@ -127,14 +166,29 @@ func (s *BucketServer) DownloadChunks(r *bucket.DownloadRequest, stream bucket.B
 		return status.Error(codes.InvalidArgument, "failed to download chunks - invalid gRPC request")
 	}
 
+	if r.GetOffset() < 0 || r.GetLength() < 0 {
+		return status.Error(codes.InvalidArgument, "failed to download chunks - invalid range")
+	}
+
 	f, err := s.fragmentStorage.Get(r.Filename, int(r.Fragment))
 	if err != nil {
 		return status.Error(codes.NotFound, "failed to download chunks - fragment is not found")
 	}
+	defer f.Close()
+
+	log.WithFields(logrus.Fields{"filename": r.GetFilename(), "fragment": r.GetFragment(), "offset": r.GetOffset(), "length": r.GetLength()}).Info("downloading fragment")
+
+	_, err = f.Seek(r.GetOffset(), io.SeekStart)
+	if err != nil {
+		return status.Error(codes.OutOfRange, "failed to download chunks - invalid offset")
+	}
 
-	log.WithFields(logrus.Fields{"filename": r.GetFilename(), "fragment": r.GetFragment()}).Info("downloading fragment")
+	var fragmentReader io.Reader = f
+	if r.GetLength() > 0 {
+		fragmentReader = io.LimitReader(f, r.GetLength())
+	}
 
-	reader := bufio.NewReader(f)
+	reader := bufio.NewReader(fragmentReader)
 	buf := make([]byte, 0, readChunkSize)
 	for {
 		n, err := io.ReadFull(reader, buf[:cap(buf)])
@@ -169,9 +223,30 @@ func (s *BucketServer) DeleteFragment(ctx context.Context, r *bucket.DeleteFragm
 	return &bucket.DeleteFragmentResponse{}, nil
 }
 
+func (s *BucketServer) StatFragment(ctx context.Context, r *bucket.StatFragmentRequest) (*bucket.StatFragmentResponse, error) {
+	size, err := s.fragmentStorage.Stat(r.GetFilename(), int(r.GetFragment()))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, status.Errorf(codes.NotFound, "%d fragment of %s is not found", r.GetFragment(), r.GetFilename())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &bucket.StatFragmentResponse{Size: size}, nil
+}
+
 func (s *BucketServer) initGRPCClient() error {
-	insecureCreds := grpc.WithTransportCredentials(insecure.NewCredentials())
-	conn, err := grpc.NewClient(*apiServerAddress, insecureCreds)
+	transport := insecure.NewCredentials()
+	if s.credentials != nil {
+		host, _, err := net.SplitHostPort(*apiServerAddress)
+		if err != nil {
+			return fmt.Errorf("invalid API server address: %w", err)
+		}
+
+		transport = s.credentials.ClientCredentials(host)
+	}
+
+	conn, err := grpc.NewClient(*apiServerAddress, grpc.WithTransportCredentials(transport))
 	if err != nil {
 		return fmt.Errorf("failed to connect to API server: %w", err)
 	}
@@ -195,7 +270,23 @@ func (s *BucketServer) initGRPCServer() error {
 		return fmt.Errorf("failed to setup gRPC network listener: %w", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             keepaliveMinTime,
+			PermitWithoutStream: true,
+		}),
+	}
+	if s.credentials != nil {
+		// only the API server could store or delete fragments, other bucket servers have certificates of the same CA
+		host, _, err := net.SplitHostPort(*apiServerAddress)
+		if err != nil {
+			return fmt.Errorf("invalid API server address: %w", err)
+		}
+
+		opts = append(opts, grpc.Creds(s.credentials.ServerCredentials(host)))
+	}
+
+	grpcServer := grpc.NewServer(opts...)
 
 	bucket.RegisterBucketServiceServer(grpcServer, s)
 	healthgrpc.RegisterHealthServer(grpcServer, health.NewServer())
@@ -209,8 +300,31 @@ func (s *BucketServer) initGRPCServer() error {
 	return nil
 }
 
+func (s *BucketServer) watchCredentials() {
+	for range time.Tick(credentialsReloadInterval) {
+		reloaded, err := s.credentials.Reload()
+		if err != nil {
+			log.WithError(err).Error("failed to reload TLS credentials, previous ones are kept")
+			continue
+		}
+
+		if reloaded {
+			log.Info("TLS credentials are reloaded")
+		}
+	}
+}
+
 func (s *BucketServer) shutdown() {
 	if s.apiServerConn != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), deregisterTimeout)
+		defer cancel()
+
+		// API server stops placing fragments on the bucket server and closes its connection
+		_, err := bucket.NewApiServiceClient(s.apiServerConn).DeregisterBucket(ctx, &bucket.DeregisterBucketRequest{Address: *address})
+		if err != nil {
+			log.WithError(err).Error("failed to deregister bucket server")
+		}
+
 		s.apiServerConn.Close()
 	}
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	bucket "github.com/aburluka/k8test/internal/proto"
+	"github.com/gorilla/websocket"
+)
+
+type (
+	// ackTracker receives server messages of the framed upload protocol.
+	ackTracker struct {
+		conn *websocket.Conn
+
+		lock      sync.Mutex
+		cond      *sync.Cond
+		nextSeq   uint64
+		committed int64
+		result    *bucket.WsUploadStatus
+		err       error
+		done      bool
+	}
+)
+
+func newAckTracker(conn *websocket.Conn) *ackTracker {
+	t := &ackTracker{
+		conn: conn,
+	}
+	t.cond = sync.NewCond(&t.lock)
+
+	return t
+}
+
+func (t *ackTracker) receive() {
+	for {
+		var msg bucket.WsServerMessage
+		err := t.conn.ReadJSON(&msg)
+
+		t.lock.Lock()
+		if err != nil {
+			t.finish(nil, fmt.Errorf("connection is lost: %w", fromCloseError(err)))
+			t.lock.Unlock()
+			return
+		}
+
+		if ack := msg.GetAck(); ack != nil {
+			t.nextSeq = max(t.nextSeq, ack.GetNextSeq())
+
+			if ack.GetCommitted() > t.committed {
+				t.committed = ack.GetCommitted()
+				log.Debugf("%d bytes are stored", t.committed)
+			}
+		}
+
+		if status := msg.GetStatus(); status != nil {
+			var err error
+			if msg.GetError() != nil {
+				err = newAPIError(msg.GetError())
+			}
+
+			t.finish(status, err)
+			t.lock.Unlock()
+			return
+		}
+
+		t.cond.Broadcast()
+		t.lock.Unlock()
+	}
+}
+
+// finish must be called with the lock held.
+func (t *ackTracker) finish(status *bucket.WsUploadStatus, err error) {
+	t.result = status
+	t.err = err
+	t.done = true
+	t.cond.Broadcast()
+}
+
+// wait blocks until the chunk with the sequence number is within the window.
+func (t *ackTracker) wait(seq, window uint64) error {
+	window = max(window, 1)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for seq >= t.nextSeq+window && !t.done {
+		t.cond.Wait()
+	}
+
+	if t.done && t.err != nil {
+		return t.err
+	}
+
+	if t.done {
+		return fmt.Errorf("upload is interrupted by the server: %s", t.result.GetError())
+	}
+
+	return nil
+}
+
+// status waits for the final upload status.
+func (t *ackTracker) status() (*bucket.WsUploadStatus, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for !t.done {
+		t.cond.Wait()
+	}
+
+	if t.result == nil && t.err == nil {
+		return nil, errors.New("no upload status is received")
+	}
+
+	return t.result, t.err
+}
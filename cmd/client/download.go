@@ -0,0 +1,363 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aburluka/k8test/internal/encryption"
+	bucket "github.com/aburluka/k8test/internal/proto"
+	"github.com/gorilla/websocket"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	downloadRetries       = 5
+	downloadRetryInterval = 2 * time.Second
+
+	downloadStateSuffix = ".download"
+
+	checksumHeader = "X-File-Checksum"
+	sizeHeader     = "X-File-Size"
+)
+
+var (
+	errFileChanged         = errors.New("file is changed on the server")
+	errRangeNotSatisfiable = errors.New("requested range is not satisfiable")
+)
+
+type (
+	// downloadState identifies the file version, which is partially downloaded to the destination.
+	// Size of the destination file is the download progress.
+	downloadState struct {
+		Checksum string `json:"checksum"`
+		Size     int64  `json:"size"`
+	}
+)
+
+func loadDownloadState(dst string) *downloadState {
+	var state downloadState
+
+	b, err := os.ReadFile(dst + downloadStateSuffix)
+	if err != nil {
+		return &state
+	}
+
+	err = json.Unmarshal(b, &state)
+	if err != nil {
+		return &downloadState{}
+	}
+
+	return &state
+}
+
+func (ds *downloadState) save(dst string) error {
+	b, err := json.Marshal(ds)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(dst+downloadStateSuffix, b, 0644)
+}
+
+func download() *cli.Command {
+	return &cli.Command{
+		Name:  "download",
+		Usage: "download a file",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "src",
+				Value: "./test-file-src.bin",
+				Usage: "file to download",
+			},
+			&cli.StringFlag{
+				Name:  "dst",
+				Value: "./test-file-dst.bin",
+				Usage: "local filename for downloaded file",
+			},
+			&cli.Int64Flag{
+				Name:  "offset",
+				Usage: "download file starting from the offset",
+			},
+			&cli.Int64Flag{
+				Name:  "length",
+				Usage: "download only length bytes, up to the end of file if not set",
+			},
+			&cli.BoolFlag{
+				Name:  "encrypt",
+				Usage: "decrypt the file, uploaded with --encrypt",
+			},
+			&cli.StringFlag{
+				Name:  "key-file",
+				Usage: "file with base64 encoded 32-byte key for --encrypt",
+			},
+			&cli.StringFlag{
+				Name:  "api-server",
+				Value: "0.0.0.0:80",
+				Usage: "api server address",
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			src, dst := cCtx.String("src"), cCtx.String("dst")
+
+			var key []byte
+			if cCtx.Bool("encrypt") {
+				var err error
+				key, err = encryption.LoadKey(cCtx.String("key-file"))
+				if err != nil {
+					log.WithError(err).Fatalln("failed to load encryption key")
+				}
+			}
+
+			// partial download is neither resumed nor verified
+			if cCtx.IsSet("offset") || cCtx.IsSet("length") {
+				outputFile, err := os.Create(dst)
+				if err != nil {
+					log.WithError(err).Fatalln("failed to create output file")
+				}
+				defer outputFile.Close()
+
+				if key != nil {
+					length := int64(-1)
+					if cCtx.IsSet("length") {
+						length = cCtx.Int64("length")
+					}
+
+					return downloadDecrypted(cCtx.String("api-server"), src, key, cCtx.Int64("offset"), length, outputFile, nil)
+				}
+
+				query := url.Values{}
+				if cCtx.IsSet("offset") {
+					query.Set("offset", strconv.FormatInt(cCtx.Int64("offset"), 10))
+				}
+				if cCtx.IsSet("length") {
+					query.Set("length", strconv.FormatInt(cCtx.Int64("length"), 10))
+				}
+
+				return downloadChunks(cCtx.String("api-server"), src, query, outputFile, nil)
+			}
+
+			state := loadDownloadState(dst)
+			for attempt := 0; ; attempt++ {
+				err := downloadFile(cCtx.String("api-server"), src, dst, key, state)
+				if err == nil {
+					break
+				}
+
+				if !errors.Is(err, errFileChanged) && !retryable(err) {
+					return err
+				}
+
+				// restarts of a repeatedly rewritten file are limited as well as resumes
+				if attempt == downloadRetries {
+					if errors.Is(err, errFileChanged) {
+						return fmt.Errorf("file keeps changing on the server, download is given up: %w", err)
+					}
+
+					return fmt.Errorf("download failed, run the command again to resume it: %w", err)
+				}
+
+				if errors.Is(err, errFileChanged) {
+					log.Warn("file is changed on the server, restarting download")
+					continue
+				}
+
+				log.WithError(err).Warnf("download is interrupted, resuming in %s", downloadRetryInterval)
+				time.Sleep(downloadRetryInterval)
+			}
+
+			os.Remove(dst + downloadStateSuffix)
+
+			// checksum is calculated for encrypted data, every decrypted segment is authenticated instead
+			if key != nil {
+				log.Infof("downloaded file %s is decrypted and authenticated", dst)
+				return nil
+			}
+
+			if len(state.Checksum) == 0 {
+				log.Warn("file has no checksum, downloaded file is not verified")
+				return nil
+			}
+
+			err := verifyChecksum(dst, state.Checksum)
+			if err != nil {
+				return err
+			}
+
+			log.Infof("downloaded file %s is verified", dst)
+
+			return nil
+		},
+	}
+}
+
+// downloadFile continues download of the file version, recorded in the state, from the destination file size.
+// End-to-end encrypted file is decrypted with the key, its download is continued from the last complete segment.
+func downloadFile(apiServer, src, dst string, key []byte, state *downloadState) error {
+	outputFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.WithError(err).Fatalln("failed to create output file")
+	}
+	defer outputFile.Close()
+
+	var offset int64
+	if len(state.Checksum) > 0 {
+		offset, err = outputFile.Seek(0, io.SeekEnd)
+		if err != nil {
+			return fmt.Errorf("failed to seek output file: %w", err)
+		}
+	}
+
+	size := state.Size
+	if key != nil {
+		offset -= offset % encryption.SegmentSize
+		size, _ = encryption.PlainFileSize(state.Size)
+	}
+
+	// nothing is downloaded yet or there is no way to find out, which version is downloaded
+	if offset == 0 || key != nil {
+		err = outputFile.Truncate(offset)
+		if err != nil {
+			return fmt.Errorf("failed to truncate output file: %w", err)
+		}
+
+		_, err = outputFile.Seek(offset, io.SeekStart)
+		if err != nil {
+			return fmt.Errorf("failed to seek output file: %w", err)
+		}
+	}
+
+	if offset > 0 && offset == size {
+		return nil
+	}
+
+	query := url.Values{}
+	if offset > 0 {
+		query.Set("offset", strconv.FormatInt(offset, 10))
+		log.Infof("resuming download from %d offset", offset)
+	}
+
+	// check that the same file version is downloaded, before anything is written
+	checkVersion := func(header http.Header) error {
+		checksum := header.Get(checksumHeader)
+		if len(state.Checksum) > 0 && state.Checksum != checksum {
+			return errFileChanged
+		}
+
+		if len(state.Checksum) == 0 && len(checksum) > 0 {
+			state.Checksum = checksum
+			state.Size, _ = strconv.ParseInt(header.Get(sizeHeader), 10, 64)
+
+			err := state.save(dst)
+			if err != nil {
+				log.WithError(err).Warn("failed to save download state, download can't be resumed")
+			}
+		}
+
+		return nil
+	}
+
+	if key != nil {
+		err = downloadDecrypted(apiServer, src, key, offset, -1, outputFile, checkVersion)
+	} else {
+		err = downloadChunks(apiServer, src, query, outputFile, checkVersion)
+	}
+	if errors.Is(err, errFileChanged) || errors.Is(err, errRangeNotSatisfiable) {
+		*state = downloadState{}
+		os.Remove(dst + downloadStateSuffix)
+		return errFileChanged
+	}
+
+	return err
+}
+
+// downloadChunks writes downloaded chunks to the output. Response header is checked before anything is written.
+func downloadChunks(apiServer, src string, query url.Values, output io.Writer, check func(http.Header) error) error {
+	u := url.URL{
+		Scheme:   "ws",
+		Host:     apiServer,
+		Path:     apiPath("/download", src),
+		RawQuery: query.Encode(),
+	}
+	log.Infof("connecting to %s", u.String())
+
+	conn, resp, err := websocket.DefaultDialer.Dial(u.String(), authHeader())
+	if resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+		return responseError(resp)
+	}
+	if resp != nil && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return errRangeNotSatisfiable
+	}
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to api-server: %w", err)
+	}
+	defer conn.Close()
+
+	if check != nil {
+		err = check(resp.Header)
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		_, b, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("failed to read chunk: %w", fromCloseError(err))
+		}
+
+		if len(b) == 0 {
+			break
+		}
+
+		_, err = output.Write(b)
+		if err != nil {
+			log.WithError(err).Fatal("failed to write chunk")
+		}
+	}
+
+	return nil
+}
+
+func fileChecksum(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func verifyChecksum(filename, checksum string) error {
+	actual, err := fileChecksum(filename)
+	if err != nil {
+		return fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+
+	if actual != checksum {
+		return &apiError{
+			code:    bucket.WsErrorCode_WS_ERROR_CHECKSUM_MISMATCH,
+			message: "downloaded file is corrupted",
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/aburluka/k8test/internal/encryption"
+)
+
+type (
+	// uploadSource provides uploaded data starting from any offset, so upload could be resumed.
+	uploadSource interface {
+		Size() int64
+		From(offset int64) (io.Reader, error)
+	}
+
+	fileSource struct {
+		f    *os.File
+		size int64
+	}
+
+	// encryptedSource produces end-to-end encrypted file: header followed by sealed segments.
+	// Encryption is deterministic for the header, so any part of the file could be produced again.
+	encryptedSource struct {
+		f      *os.File
+		size   int64
+		header []byte
+		cipher *encryption.SegmentCipher
+	}
+
+	encryptedReader struct {
+		source  *encryptedSource
+		segment int64
+		pending []byte
+		plain   []byte
+	}
+
+	// decryptingWriter opens segments of end-to-end encrypted file written to it and writes plaintext
+	// to the output, skipping bytes before the requested range.
+	decryptingWriter struct {
+		cipher    *encryption.SegmentCipher
+		size      int64 // plaintext size of the whole file
+		segment   int64
+		skip      int64
+		remaining int64 // negative means up to the end of file
+		buf       []byte
+		plain     []byte
+		output    io.Writer
+	}
+)
+
+// newEncryptedSource continues encryption of interrupted upload with the same header, so the stored part
+// stays valid. Upload encrypted differently or with another key is started from scratch.
+func newEncryptedSource(f *os.File, size int64, key []byte, state *uploadState) (*encryptedSource, error) {
+	s := &encryptedSource{
+		f:      f,
+		size:   size,
+		header: state.Header,
+	}
+
+	var err error
+	if len(s.header) > 0 {
+		s.cipher, err = encryption.ParseFileHeader(key, s.header)
+		if err == nil {
+			return s, nil
+		}
+
+		log.WithError(err).Warn("interrupted upload can't be resumed, starting a new one")
+	}
+
+	s.header, s.cipher, err = encryption.NewFileHeader(key)
+	if err != nil {
+		return nil, err
+	}
+
+	state.UploadID = ""
+	state.Header = s.header
+
+	return s, nil
+}
+
+func sourceChecksum(source uploadSource) (string, error) {
+	r, err := source.From(0)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	_, err = io.Copy(h, r)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *fileSource) Size() int64 {
+	return s.size
+}
+
+func (s *fileSource) From(offset int64) (io.Reader, error) {
+	_, err := s.f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.f, nil
+}
+
+func (s *encryptedSource) Size() int64 {
+	return encryption.EncryptedFileSize(s.size)
+}
+
+func (s *encryptedSource) From(offset int64) (io.Reader, error) {
+	r := &encryptedReader{
+		source: s,
+	}
+
+	if offset < int64(len(s.header)) {
+		r.pending = s.header[offset:]
+		return r, nil
+	}
+
+	r.segment = (offset - encryption.SegmentOffset(0)) / (encryption.SegmentSize + encryption.Overhead)
+	skip := offset - encryption.SegmentOffset(r.segment)
+
+	err := r.seal()
+	if err != nil {
+		return nil, err
+	}
+
+	r.pending = r.pending[min(skip, int64(len(r.pending))):]
+
+	return r, nil
+}
+
+func (r *encryptedReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.segment >= encryption.FileSegments(r.source.size) {
+			return 0, io.EOF
+		}
+
+		err := r.seal()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+
+	return n, nil
+}
+
+// seal reads the next segment from the file and seals it.
+func (r *encryptedReader) seal() error {
+	if r.plain == nil {
+		r.plain = make([]byte, encryption.SegmentSize)
+	}
+
+	offset := r.segment * encryption.SegmentSize
+	n := min(int64(encryption.SegmentSize), max(0, r.source.size-offset))
+
+	_, err := r.source.f.ReadAt(r.plain[:n], offset)
+	if err != nil && n > 0 {
+		return fmt.Errorf("failed to read from file: %w", err)
+	}
+
+	final := r.segment == encryption.FileSegments(r.source.size)-1
+	r.pending = r.source.cipher.Seal(r.pending[:0], r.plain[:n], uint32(r.segment), final)
+	r.segment++
+
+	return nil
+}
+
+func (w *decryptingWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		sealedSize := w.sealedSize()
+		if sealedSize == 0 {
+			return 0, fmt.Errorf("received more than %d segments", encryption.FileSegments(w.size))
+		}
+
+		n := min(len(p), sealedSize-len(w.buf))
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+
+		if len(w.buf) < sealedSize {
+			continue
+		}
+
+		err := w.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return written, nil
+}
+
+// sealedSize returns size of the next segment, the last one is shorter.
+func (w *decryptingWriter) sealedSize() int {
+	left := w.size - w.segment*encryption.SegmentSize
+	if w.segment >= encryption.FileSegments(w.size) {
+		return 0
+	}
+
+	return int(min(left, encryption.SegmentSize)) + encryption.Overhead
+}
+
+func (w *decryptingWriter) open() error {
+	final := w.segment == encryption.FileSegments(w.size)-1
+
+	plain, err := w.cipher.Open(w.plain[:0], w.buf, uint32(w.segment), final)
+	if err != nil {
+		return err
+	}
+	w.plain = plain
+	w.buf = w.buf[:0]
+	w.segment++
+
+	skip := min(w.skip, int64(len(plain)))
+	plain = plain[skip:]
+	w.skip -= skip
+
+	if w.remaining >= 0 {
+		plain = plain[:min(w.remaining, int64(len(plain)))]
+		w.remaining -= int64(len(plain))
+	}
+
+	_, err = w.output.Write(plain)
+
+	return err
+}
+
+// Close checks that the whole range is received.
+func (w *decryptingWriter) Close() error {
+	if len(w.buf) > 0 || w.remaining > 0 || (w.remaining < 0 && w.segment != encryption.FileSegments(w.size)) {
+		return encryption.ErrTruncated
+	}
+
+	return nil
+}
+
+// downloadDecrypted downloads length bytes of end-to-end encrypted file starting from the plaintext offset,
+// negative length means up to the end of file. Only segments containing the range are downloaded.
+func downloadDecrypted(apiServer, src string, key []byte, offset, length int64, output io.Writer, check func(http.Header) error) error {
+	var (
+		header        bytes.Buffer
+		encryptedSize int64
+		checksum      string
+	)
+
+	query := url.Values{}
+	query.Set("offset", "0")
+	query.Set("length", strconv.Itoa(encryption.FileHeaderSize))
+
+	err := downloadChunks(apiServer, src, query, &header, func(h http.Header) error {
+		encryptedSize, _ = strconv.ParseInt(h.Get(sizeHeader), 10, 64)
+		checksum = h.Get(checksumHeader)
+		if check != nil {
+			return check(h)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c, err := encryption.ParseFileHeader(key, header.Bytes())
+	if err != nil {
+		return err
+	}
+
+	size, err := encryption.PlainFileSize(encryptedSize)
+	if err != nil {
+		return err
+	}
+
+	if offset < 0 || offset > size || (length >= 0 && offset+length > size) {
+		return errRangeNotSatisfiable
+	}
+
+	if offset == size || length == 0 {
+		return nil
+	}
+
+	first := offset / encryption.SegmentSize
+	last := encryption.FileSegments(size) - 1
+	if length >= 0 {
+		last = (offset + length - 1) / encryption.SegmentSize
+	}
+
+	start := encryption.SegmentOffset(first)
+	end := min(encryption.SegmentOffset(last+1), encryptedSize)
+
+	query = url.Values{}
+	query.Set("offset", strconv.FormatInt(start, 10))
+	query.Set("length", strconv.FormatInt(end-start, 10))
+
+	w := &decryptingWriter{
+		cipher:    c,
+		size:      size,
+		segment:   first,
+		skip:      offset - first*encryption.SegmentSize,
+		remaining: length,
+		output:    output,
+	}
+
+	// segments must belong to the same file version as the header
+	err = downloadChunks(apiServer, src, query, w, func(h http.Header) error {
+		if h.Get(checksumHeader) != checksum {
+			return errFileChanged
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.Close()
+}
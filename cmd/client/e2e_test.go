@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aburluka/k8test/internal/encryption"
+)
+
+func testFile(t *testing.T, size int) (*os.File, []byte) {
+	t.Helper()
+
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+
+	path := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(path, data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	return f, data
+}
+
+func testEncryptionKey(seed int64) []byte {
+	key := make([]byte, encryption.KeySize)
+	rand.New(rand.NewSource(seed)).Read(key)
+
+	return key
+}
+
+func readFrom(t *testing.T, source uploadSource, offset int64) []byte {
+	t.Helper()
+
+	r, err := source.From(offset)
+	if err != nil {
+		t.Fatalf("From(%d) error = %v", offset, err)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read from %d offset error = %v", offset, err)
+	}
+
+	return b
+}
+
+func TestEncryptedSourceResume(t *testing.T) {
+	const size = 3*encryption.SegmentSize + 100
+
+	tests := []struct {
+		name   string
+		offset int64
+	}{
+		{name: "from the start", offset: 0},
+		{name: "inside header", offset: 5},
+		{name: "after header", offset: encryption.SegmentOffset(0)},
+		{name: "inside first segment", offset: encryption.SegmentOffset(0) + 1000},
+		{name: "inside tag of first segment", offset: encryption.SegmentOffset(1) - 3},
+		{name: "segment boundary", offset: encryption.SegmentOffset(2)},
+		{name: "inside last segment", offset: encryption.SegmentOffset(3) + 50},
+		{name: "end of file", offset: encryption.SegmentOffset(3) + 100 + encryption.Overhead},
+	}
+
+	f, data := testFile(t, size)
+	key := testEncryptionKey(1)
+
+	state := &uploadState{UploadID: "upload"}
+	source, err := newEncryptedSource(f, size, key, state)
+	if err != nil {
+		t.Fatalf("newEncryptedSource() error = %v", err)
+	}
+
+	if state.UploadID != "" {
+		t.Errorf("upload ID = %q of the new encrypted upload, want empty", state.UploadID)
+	}
+
+	full := readFrom(t, source, 0)
+	if int64(len(full)) != source.Size() || source.Size() != encryption.SegmentOffset(3)+100+encryption.Overhead {
+		t.Fatalf("encrypted %d bytes, size = %d", len(full), source.Size())
+	}
+
+	// the next client run continues the upload with the saved header
+	resumedState := &uploadState{UploadID: "upload", Header: state.Header}
+	resumed, err := newEncryptedSource(f, size, key, resumedState)
+	if err != nil {
+		t.Fatalf("newEncryptedSource() error = %v", err)
+	}
+
+	if resumedState.UploadID != "upload" {
+		t.Errorf("upload ID = %q of the resumed upload, want %q", resumedState.UploadID, "upload")
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := readFrom(t, resumed, tt.offset)
+			if !bytes.Equal(got, full[tt.offset:]) {
+				t.Fatalf("resumed from %d offset data differs from the encrypted file", tt.offset)
+			}
+
+			// the uploaded file is decrypted whatever offset the upload is resumed from
+			var plain bytes.Buffer
+			w := &decryptingWriter{
+				cipher:    source.cipher,
+				size:      size,
+				remaining: -1,
+				output:    &plain,
+			}
+
+			// the part stored before the interruption is followed by the resumed part
+			stored := append(bytes.Clone(full[:tt.offset]), got...)
+			for p := stored[encryption.FileHeaderSize:]; len(p) > 0; {
+				n := min(len(p), 1000)
+				_, err := w.Write(p[:n])
+				if err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+				p = p[n:]
+			}
+
+			err := w.Close()
+			if err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			if !bytes.Equal(plain.Bytes(), data) {
+				t.Errorf("decrypted %d bytes, which differ from %d bytes of the file", plain.Len(), len(data))
+			}
+		})
+	}
+}
+
+func TestEncryptedSourceAnotherKey(t *testing.T) {
+	f, _ := testFile(t, 1000)
+
+	state := &uploadState{UploadID: "upload"}
+	_, err := newEncryptedSource(f, 1000, testEncryptionKey(1), state)
+	if err != nil {
+		t.Fatalf("newEncryptedSource() error = %v", err)
+	}
+	header := state.Header
+
+	// upload encrypted with another key can't be continued, it is started again
+	state.UploadID = "upload"
+	_, err = newEncryptedSource(f, 1000, testEncryptionKey(2), state)
+	if err != nil {
+		t.Fatalf("newEncryptedSource() error = %v", err)
+	}
+
+	if state.UploadID != "" {
+		t.Errorf("upload ID = %q, want empty", state.UploadID)
+	}
+
+	if bytes.Equal(state.Header, header) {
+		t.Error("header is kept for another key")
+	}
+}
+
+func TestDecryptingWriterRange(t *testing.T) {
+	const size = 3*encryption.SegmentSize + 100
+
+	tests := []struct {
+		name   string
+		offset int64
+		length int64
+	}{
+		{name: "whole file", offset: 0, length: -1},
+		{name: "first bytes", offset: 0, length: 10},
+		{name: "inside segment", offset: 1000, length: 2000},
+		{name: "across segments", offset: encryption.SegmentSize - 10, length: encryption.SegmentSize + 20},
+		{name: "from segment to the end", offset: 2*encryption.SegmentSize + 5, length: -1},
+		{name: "last byte", offset: size - 1, length: 1},
+	}
+
+	f, data := testFile(t, size)
+	key := testEncryptionKey(1)
+
+	source, err := newEncryptedSource(f, size, key, &uploadState{})
+	if err != nil {
+		t.Fatalf("newEncryptedSource() error = %v", err)
+	}
+	full := readFrom(t, source, 0)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := tt.offset / encryption.SegmentSize
+			last := encryption.FileSegments(size) - 1
+			want := data[tt.offset:]
+			if tt.length >= 0 {
+				last = (tt.offset + tt.length - 1) / encryption.SegmentSize
+				want = want[:tt.length]
+			}
+
+			var plain bytes.Buffer
+			w := &decryptingWriter{
+				cipher:    source.cipher,
+				size:      size,
+				segment:   first,
+				skip:      tt.offset - first*encryption.SegmentSize,
+				remaining: tt.length,
+				output:    &plain,
+			}
+
+			sealed := full[encryption.SegmentOffset(first):min(encryption.SegmentOffset(last+1), int64(len(full)))]
+			_, err := w.Write(sealed)
+			if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			err = w.Close()
+			if err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			if !bytes.Equal(plain.Bytes(), want) {
+				t.Errorf("decrypted %d bytes, want %d bytes of the range", plain.Len(), len(want))
+			}
+		})
+	}
+
+	t.Run("truncated range", func(t *testing.T) {
+		w := &decryptingWriter{
+			cipher:    source.cipher,
+			size:      size,
+			remaining: -1,
+			output:    io.Discard,
+		}
+
+		_, err := w.Write(full[encryption.FileHeaderSize : len(full)-10])
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+
+		err = w.Close()
+		if !errors.Is(err, encryption.ErrTruncated) {
+			t.Errorf("Close() error = %v, want %v", err, encryption.ErrTruncated)
+		}
+	})
+}
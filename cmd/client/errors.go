@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aburluka/k8test/internal/encryption"
+	bucket "github.com/aburluka/k8test/internal/proto"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// application close codes are 4000 + error code
+	wsCloseCodeBase = 4000
+
+	exitCodeFailure  = 1
+	exitCodeWrongKey = 9
+)
+
+type (
+	// apiError is an error, reported by api-server with a machine-readable code.
+	apiError struct {
+		code    bucket.WsErrorCode
+		message string
+	}
+)
+
+var (
+	exitCodes = map[bucket.WsErrorCode]int{
+		bucket.WsErrorCode_WS_ERROR_BAD_REQUEST:         2,
+		bucket.WsErrorCode_WS_ERROR_NOT_FOUND:           3,
+		bucket.WsErrorCode_WS_ERROR_NO_CAPACITY:         4,
+		bucket.WsErrorCode_WS_ERROR_FILE_EXISTS:         5,
+		bucket.WsErrorCode_WS_ERROR_CHECKSUM_MISMATCH:   6,
+		bucket.WsErrorCode_WS_ERROR_QUOTA_EXCEEDED:      7,
+		bucket.WsErrorCode_WS_ERROR_UPLOAD_ACTIVE:       8,
+		bucket.WsErrorCode_WS_ERROR_NAMESPACE_NOT_EMPTY: 10,
+		bucket.WsErrorCode_WS_ERROR_UNAUTHENTICATED:     11,
+		bucket.WsErrorCode_WS_ERROR_FORBIDDEN:           12,
+	}
+
+	// errors, which could disappear by themselves, so the operation is retried
+	retryableCodes = map[bucket.WsErrorCode]bool{
+		bucket.WsErrorCode_WS_ERROR_INTERNAL:      true,
+		bucket.WsErrorCode_WS_ERROR_NO_CAPACITY:   true,
+		bucket.WsErrorCode_WS_ERROR_UPLOAD_ACTIVE: true,
+	}
+
+	errNotFound = &apiError{code: bucket.WsErrorCode_WS_ERROR_NOT_FOUND, message: "file is not found"}
+)
+
+func newAPIError(wsErr *bucket.WsError) *apiError {
+	return &apiError{
+		code:    wsErr.GetCode(),
+		message: wsErr.GetMessage(),
+	}
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.reason(), e.message)
+}
+
+func (e *apiError) reason() string {
+	return bucket.WsErrorCode_name[int32(e.code)]
+}
+
+// responseError converts error response of api-server to apiError.
+func responseError(resp *http.Response) error {
+	var wsErr bucket.WsError
+	err := json.NewDecoder(resp.Body).Decode(&wsErr)
+	if err != nil || wsErr.GetCode() == bucket.WsErrorCode_WS_ERROR_NONE {
+		return fmt.Errorf("unexpected api-server response: %s", resp.Status)
+	}
+
+	return newAPIError(&wsErr)
+}
+
+// fromCloseError converts WebSocket close error with the application close code to apiError.
+func fromCloseError(err error) error {
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code < wsCloseCodeBase {
+		return err
+	}
+
+	code := bucket.WsErrorCode(closeErr.Code - wsCloseCodeBase)
+	if _, ok := bucket.WsErrorCode_name[int32(code)]; !ok {
+		return err
+	}
+
+	return &apiError{
+		code:    code,
+		message: closeErr.Text,
+	}
+}
+
+// retryable reports whether the operation could succeed, if it is repeated.
+func retryable(err error) bool {
+	// encrypted data doesn't change, when download is repeated
+	if errors.Is(err, encryption.ErrWrongKey) || errors.Is(err, encryption.ErrNotEncrypted) || errors.Is(err, encryption.ErrDecrypt) {
+		return false
+	}
+
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return retryableCodes[apiErr.code]
+	}
+
+	return true
+}
+
+func exitCode(err error) int {
+	if errors.Is(err, encryption.ErrWrongKey) || errors.Is(err, encryption.ErrNotEncrypted) {
+		return exitCodeWrongKey
+	}
+
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		if code, ok := exitCodes[apiErr.code]; ok {
+			return code
+		}
+	}
+
+	return exitCodeFailure
+}
@@ -1,15 +1,17 @@
 package main
 
 import (
-	"bufio"
-	"errors"
-	"io"
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"slices"
+	"strconv"
+	"strings"
 
 	bucket "github.com/aburluka/k8test/internal/proto"
-	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
 )
@@ -20,31 +22,53 @@ const (
 
 var (
 	log = logrus.New()
+
+	// namespace of files, the default one is used when it is empty
+	namespace string
+	// API token, requests are sent without it, when it is empty
+	token string
 )
 
 func main() {
 	app := &cli.App{
 		Name: "karma-test-client",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "namespace",
+				Usage:       "namespace of files, the default one when not set",
+				Destination: &namespace,
+			},
+			&cli.StringFlag{
+				Name:        "token",
+				Usage:       "API token, required when the api-server authenticates requests",
+				EnvVars:     []string{"K8TEST_TOKEN"},
+				Destination: &token,
+			},
+		},
 		Commands: []*cli.Command{
 			upload(),
 			download(),
+			deleteFile(),
+			list(),
+			stat(),
 		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
-		log.WithError(err).Fatalln("client failed")
+		log.WithError(err).Error("client failed")
+		os.Exit(exitCode(err))
 	}
 }
 
-func upload() *cli.Command {
+func deleteFile() *cli.Command {
 	return &cli.Command{
-		Name:  "upload",
-		Usage: "upload a file",
+		Name:  "delete",
+		Usage: "delete a file",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:  "src",
 				Value: "./test-file-src.bin",
-				Usage: "file to upload",
+				Usage: "file to delete",
 			},
 			&cli.StringFlag{
 				Name:  "api-server",
@@ -53,58 +77,52 @@ func upload() *cli.Command {
 			},
 		},
 		Action: func(cCtx *cli.Context) error {
-			filename := cCtx.String("src")
 			u := url.URL{
-				Scheme: "ws",
+				Scheme: "http",
 				Host:   cCtx.String("api-server"),
-				Path:   path.Join("/upload/", filename),
+				Path:   apiPath("/files", cCtx.String("src")),
 			}
-			log.Infof("connecting to %s", u.String())
+			log.Infof("deleting %s", u.String())
 
-			conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+			req, err := http.NewRequest(http.MethodDelete, u.String(), nil)
 			if err != nil {
-				log.WithError(err).Fatalln("failed to connect to api-server")
+				return err
 			}
-			defer conn.Close()
+			req.Header = authHeader()
 
-			fileInfo, err := os.Stat(filename)
+			resp, err := http.DefaultClient.Do(req)
 			if err != nil {
-				log.WithError(err).WithField("filename", filename).Fatalln("failed to get file info")
+				log.WithError(err).Fatalln("failed to connect to api-server")
 			}
+			defer resp.Body.Close()
 
-			err = conn.WriteJSON(bucket.WsFileInfo{
-				Size: fileInfo.Size(),
-			})
-			if err != nil {
-				log.WithError(err).Fatalln("failed to send file info")
+			if resp.StatusCode == http.StatusNotFound {
+				return errNotFound
 			}
 
-			f, err := os.Open(filename)
+			if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+				return responseError(resp)
+			}
+
+			var report bucket.DeleteFileReport
+			err = json.NewDecoder(resp.Body).Decode(&report)
 			if err != nil {
-				log.WithError(err).WithField("filename", filename).Fatalln("failed to open file for reading")
+				return fmt.Errorf("failed to decode delete report: %w", err)
 			}
 
-			r := bufio.NewReader(f)
-			buf := make([]byte, 0, readChunkSize)
-			for {
-				n, err := io.ReadFull(r, buf[:cap(buf)])
-				buf = buf[:n]
-				if errors.Is(err, io.EOF) {
-					break
-				}
-				if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
-					log.WithError(err).WithField("filename", filename).Fatalln("failed to read from file")
-				}
+			if report.GetDeferred() {
+				log.Infof("file %s is deleted, its fragments will be removed by the cleanup process", report.GetFilename())
+				return nil
+			}
 
-				err = conn.WriteMessage(websocket.BinaryMessage, buf)
-				if err != nil {
-					log.WithError(err).Fatalln("failed to sent chunk")
-				}
+			for _, f := range report.GetFailures() {
+				log.WithField("address", f.GetAddress()).Errorf("failed to delete %d fragment: %s", f.GetFragment(), f.GetError())
 			}
 
-			err = conn.WriteMessage(websocket.BinaryMessage, []byte{})
-			if err != nil {
-				log.WithError(err).Fatalln("failed to sent terminal message")
+			log.Infof("file %s is deleted, %d of %d fragments removed", report.GetFilename(), report.GetDeleted(), report.GetFragments())
+
+			if len(report.GetFailures()) > 0 {
+				return fmt.Errorf("%d fragments are not removed yet, they will be cleaned up later", len(report.GetFailures()))
 			}
 
 			return nil
@@ -112,20 +130,70 @@ func upload() *cli.Command {
 	}
 }
 
-func download() *cli.Command {
+// apiPath returns path of API server endpoint in the namespace.
+func apiPath(elem ...string) string {
+	if len(namespace) == 0 {
+		return path.Join(elem...)
+	}
+
+	return path.Join(append([]string{"/ns", namespace}, elem...)...)
+}
+
+// authHeader returns request header with the API token.
+func authHeader() http.Header {
+	header := http.Header{}
+	if len(token) > 0 {
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	return header
+}
+
+func getJSON(u url.URL, v any) error {
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header = authHeader()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to api-server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func list() *cli.Command {
 	return &cli.Command{
-		Name:  "download",
-		Usage: "download a file",
+		Name:  "ls",
+		Usage: "list files",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
-				Name:  "src",
-				Value: "./test-file-src.bin",
-				Usage: "file to download",
+				Name:  "prefix",
+				Usage: "list only files with the prefix",
 			},
 			&cli.StringFlag{
-				Name:  "dst",
-				Value: "./test-file-dst.bin",
-				Usage: "local filename for downloaded file",
+				Name:  "cursor",
+				Usage: "continue listing after the cursor",
+			},
+			&cli.IntFlag{
+				Name:  "limit",
+				Value: 100,
+				Usage: "maximum number of files to list",
+			},
+			&cli.StringSliceFlag{
+				Name:  "meta",
+				Usage: "list only files with the metadata key, or key=value, could be repeated",
 			},
 			&cli.StringFlag{
 				Name:  "api-server",
@@ -134,42 +202,97 @@ func download() *cli.Command {
 			},
 		},
 		Action: func(cCtx *cli.Context) error {
+			query := url.Values{}
+			query.Set("prefix", cCtx.String("prefix"))
+			query.Set("cursor", cCtx.String("cursor"))
+			query.Set("limit", strconv.Itoa(cCtx.Int("limit")))
+			for _, condition := range cCtx.StringSlice("meta") {
+				query.Add("meta", condition)
+			}
+
 			u := url.URL{
-				Scheme: "ws",
-				Host:   cCtx.String("api-server"),
-				Path:   path.Join("/download", cCtx.String("src")),
+				Scheme:   "http",
+				Host:     cCtx.String("api-server"),
+				Path:     apiPath("/files"),
+				RawQuery: query.Encode(),
 			}
-			log.Infof("connecting to %s", u.String())
 
-			conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+			var list bucket.FileList
+			err := getJSON(u, &list)
 			if err != nil {
-				log.WithError(err).Fatalln("failed to connect to api-server")
+				return err
+			}
+
+			for _, f := range list.GetFiles() {
+				fmt.Printf("%-40s %12d %-10s %s\n", f.GetFilename(), f.GetSize(), f.GetStatus(), f.GetCompletedAt())
+			}
+
+			if len(list.GetNextCursor()) > 0 {
+				fmt.Printf("more files available, use --cursor=%s\n", list.GetNextCursor())
+			}
+
+			return nil
+		},
+	}
+}
+
+func stat() *cli.Command {
+	return &cli.Command{
+		Name:  "stat",
+		Usage: "show file details",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "src",
+				Value: "./test-file-src.bin",
+				Usage: "file to show",
+			},
+			&cli.StringFlag{
+				Name:  "api-server",
+				Value: "0.0.0.0:80",
+				Usage: "api server address",
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			u := url.URL{
+				Scheme: "http",
+				Host:   cCtx.String("api-server"),
+				Path:   apiPath("/files", cCtx.String("src")),
 			}
-			defer conn.Close()
 
-			outputFile, err := os.Create(cCtx.String("dst"))
+			var f bucket.FileStat
+			err := getJSON(u, &f)
 			if err != nil {
-				log.WithError(err).Fatalln("failed to create output file")
+				return err
 			}
-			defer outputFile.Close()
 
-			for {
-				_, b, err := conn.ReadMessage()
-				if errors.Is(err, io.EOF) {
-					break
-				}
-				if err != nil {
-					log.WithError(err).Fatal("failed to read chunk")
-				}
+			fmt.Printf("namespace: %s\n", f.GetNamespace())
+			fmt.Printf("filename:  %s\n", f.GetFilename())
+			fmt.Printf("size:      %d\n", f.GetSize())
+			fmt.Printf("status:    %s\n", f.GetStatus())
+			fmt.Printf("checksum:  %s\n", f.GetChecksum())
+			fmt.Printf("codec:     %s\n", f.GetCodec())
+			fmt.Printf("encrypted: %t\n", f.GetEncrypted())
+			fmt.Printf("type:      %s\n", f.GetContentType())
+			fmt.Printf("created:   %s\n", f.GetCreatedAt())
+			fmt.Printf("completed: %s\n", f.GetCompletedAt())
 
-				if len(b) == 0 {
-					break
-				}
+			keys := make([]string, 0, len(f.GetMetadata()))
+			for key := range f.GetMetadata() {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			for _, key := range keys {
+				fmt.Printf("meta:      %s=%s\n", key, f.GetMetadata()[key])
+			}
 
-				_, err = outputFile.Write(b)
-				if err != nil {
-					log.WithError(err).Fatal("failed to write chunk")
+			fmt.Printf("fragments: %d\n", f.GetFragments())
+			for _, p := range f.GetPlacement() {
+				replicas := p.GetReplicas()
+				if len(replicas) == 0 {
+					replicas = []string{p.GetAddress()}
 				}
+
+				fmt.Printf("  %3d %12d %-30s %12d %s\n", p.GetFragment(), p.GetOffset(), strings.Join(replicas, ","), p.GetSize(), p.GetChecksum())
 			}
 
 			return nil
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aburluka/k8test/internal/encryption"
+	bucket "github.com/aburluka/k8test/internal/proto"
+	"github.com/aburluka/k8test/internal/wsproto"
+	"github.com/gorilla/websocket"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	uploadRetries       = 5
+	uploadRetryInterval = 2 * time.Second
+
+	uploadStateSuffix = ".upload"
+)
+
+type (
+	uploadOptions struct {
+		exclusive bool
+		checksum  string
+		codec     string
+
+		contentType string
+		metadata    map[string]string
+	}
+
+	// uploadState is kept next to the uploaded file, so interrupted upload could be resumed
+	// by the next client run as well.
+	uploadState struct {
+		UploadID string    `json:"upload_id"`
+		Size     int64     `json:"size"`
+		ModTime  time.Time `json:"mod_time"`
+		Header   []byte    `json:"header,omitempty"` // header of end-to-end encrypted upload
+	}
+)
+
+func loadUploadState(filename string, fileInfo os.FileInfo) *uploadState {
+	b, err := os.ReadFile(filename + uploadStateSuffix)
+	if err != nil {
+		return &uploadState{}
+	}
+
+	var state uploadState
+	err = json.Unmarshal(b, &state)
+
+	// the file was changed since interrupted upload, it must be uploaded from scratch
+	if err != nil || state.Size != fileInfo.Size() || !state.ModTime.Equal(fileInfo.ModTime()) {
+		return &uploadState{}
+	}
+
+	return &state
+}
+
+func (us *uploadState) save(filename string) error {
+	b, err := json.Marshal(us)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename+uploadStateSuffix, b, 0644)
+}
+
+func upload() *cli.Command {
+	return &cli.Command{
+		Name:  "upload",
+		Usage: "upload a file",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "src",
+				Value: "./test-file-src.bin",
+				Usage: "file to upload",
+			},
+			&cli.BoolFlag{
+				Name:  "exclusive",
+				Usage: "fail, if the file already exists",
+			},
+			&cli.BoolFlag{
+				Name:  "verify",
+				Usage: "calculate file checksum before upload, so the server verifies received data",
+			},
+			&cli.StringFlag{
+				Name:  "codec",
+				Usage: "compression codec of stored fragments: none, gzip or zstd, server default when not set",
+			},
+			&cli.StringFlag{
+				Name:  "content-type",
+				Usage: "media type of the file, sent with downloads",
+			},
+			&cli.StringSliceFlag{
+				Name:  "meta",
+				Usage: "user metadata of the file as key=value, could be repeated",
+			},
+			&cli.BoolFlag{
+				Name:  "encrypt",
+				Usage: "encrypt the file before upload, so neither api-server nor buckets see its content",
+			},
+			&cli.StringFlag{
+				Name:  "key-file",
+				Usage: "file with base64 encoded 32-byte key for --encrypt",
+			},
+			&cli.StringFlag{
+				Name:  "api-server",
+				Value: "0.0.0.0:80",
+				Usage: "api server address",
+			},
+		},
+		Action: func(cCtx *cli.Context) error {
+			filename := cCtx.String("src")
+
+			fileInfo, err := os.Stat(filename)
+			if err != nil {
+				log.WithError(err).WithField("filename", filename).Fatalln("failed to get file info")
+			}
+
+			f, err := os.Open(filename)
+			if err != nil {
+				log.WithError(err).WithField("filename", filename).Fatalln("failed to open file for reading")
+			}
+			defer f.Close()
+
+			state := loadUploadState(filename, fileInfo)
+			state.Size = fileInfo.Size()
+			state.ModTime = fileInfo.ModTime()
+
+			var source uploadSource = &fileSource{f: f, size: fileInfo.Size()}
+			if cCtx.Bool("encrypt") {
+				key, err := encryption.LoadKey(cCtx.String("key-file"))
+				if err != nil {
+					log.WithError(err).Fatalln("failed to load encryption key")
+				}
+
+				source, err = newEncryptedSource(f, fileInfo.Size(), key, state)
+				if err != nil {
+					log.WithError(err).Fatalln("failed to start encryption")
+				}
+			} else if len(state.Header) > 0 {
+				// interrupted upload is encrypted, plain file must be uploaded from scratch
+				state = &uploadState{Size: state.Size, ModTime: state.ModTime}
+			}
+
+			opts := uploadOptions{
+				exclusive:   cCtx.Bool("exclusive"),
+				codec:       cCtx.String("codec"),
+				contentType: cCtx.String("content-type"),
+				metadata:    make(map[string]string),
+			}
+
+			for _, pair := range cCtx.StringSlice("meta") {
+				key, value, ok := strings.Cut(pair, "=")
+				if !ok {
+					log.WithField("meta", pair).Fatalln("metadata must be set as key=value")
+				}
+
+				opts.metadata[key] = value
+			}
+
+			if cCtx.Bool("verify") {
+				opts.checksum, err = sourceChecksum(source)
+				if err != nil {
+					log.WithError(err).WithField("filename", filename).Fatalln("failed to calculate file checksum")
+				}
+			}
+
+			for attempt := 0; ; attempt++ {
+				err = uploadFile(cCtx.String("api-server"), filename, source, state, opts)
+				if err == nil {
+					break
+				}
+
+				if !retryable(err) {
+					os.Remove(filename + uploadStateSuffix)
+					return err
+				}
+
+				if attempt == uploadRetries {
+					return fmt.Errorf("upload failed, run the command again to resume it: %w", err)
+				}
+
+				log.WithError(err).Warnf("upload is interrupted, resuming in %s", uploadRetryInterval)
+				time.Sleep(uploadRetryInterval)
+			}
+
+			os.Remove(filename + uploadStateSuffix)
+
+			return nil
+		},
+	}
+}
+
+// uploadFile sends the file starting from the offset, the server has already stored, and waits for upload status.
+func uploadFile(apiServer, filename string, source uploadSource, state *uploadState, opts uploadOptions) error {
+	u := url.URL{
+		Scheme: "ws",
+		Host:   apiServer,
+		Path:   apiPath("/upload", filename),
+	}
+	log.Infof("connecting to %s", u.String())
+
+	conn, resp, err := websocket.DefaultDialer.Dial(u.String(), authHeader())
+	if resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+		return responseError(resp)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to api-server: %w", err)
+	}
+	defer conn.Close()
+
+	err = conn.WriteJSON(bucket.WsFileInfo{
+		Size:        source.Size(),
+		UploadId:    state.UploadID,
+		Version:     bucket.WsProtocolVersion_WS_PROTOCOL_FRAMED,
+		Exclusive:   opts.exclusive,
+		Checksum:    opts.checksum,
+		Codec:       opts.codec,
+		ContentType: opts.contentType,
+		Metadata:    opts.metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send file info: %w", err)
+	}
+
+	var session bucket.WsUploadSession
+	err = conn.ReadJSON(&session)
+	if err != nil {
+		return fmt.Errorf("failed to start upload session: %w", fromCloseError(err))
+	}
+
+	if session.GetVersion() != bucket.WsProtocolVersion_WS_PROTOCOL_FRAMED {
+		return errors.New("api-server doesn't support framed upload protocol")
+	}
+
+	if state.UploadID != session.GetUploadId() {
+		state.UploadID = session.GetUploadId()
+
+		err = state.save(filename)
+		if err != nil {
+			log.WithError(err).Warn("failed to save upload state, upload can't be resumed by the next run")
+		}
+	}
+
+	if session.GetOffset() > 0 {
+		log.Infof("resuming upload %s from %d offset", session.GetUploadId(), session.GetOffset())
+	}
+
+	data, err := source.From(session.GetOffset())
+	if err != nil {
+		return fmt.Errorf("failed to seek file: %w", err)
+	}
+
+	acks := newAckTracker(conn)
+	go acks.receive()
+
+	r := bufio.NewReader(data)
+	buf := make([]byte, 0, readChunkSize)
+	for seq := uint64(0); ; seq++ {
+		n, err := io.ReadFull(r, buf[:cap(buf)])
+		buf = buf[:n]
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			log.WithError(err).WithField("filename", filename).Fatalln("failed to read from file")
+		}
+
+		// do not send more than window chunks ahead of server acknowledgement
+		err = acks.wait(seq, uint64(session.GetWindow()))
+		if err != nil {
+			return err
+		}
+
+		// empty frame terminates the upload
+		err = conn.WriteMessage(websocket.BinaryMessage, wsproto.EncodeFrame(seq, buf))
+		if err != nil {
+			return fmt.Errorf("failed to sent chunk: %w", err)
+		}
+
+		if n == 0 {
+			break
+		}
+	}
+
+	status, err := acks.status()
+	if err != nil {
+		return err
+	}
+
+	if len(status.GetError()) > 0 {
+		return fmt.Errorf("upload is %s: %s", status.GetStatus(), status.GetError())
+	}
+
+	log.WithField("checksum", status.GetChecksum()).Infof("upload is %s, %d bytes stored", status.GetStatus(), status.GetSize())
+
+	return nil
+}
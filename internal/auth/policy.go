@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ActionUpload   = "upload"
+	ActionDownload = "download"
+	ActionDelete   = "delete"
+	ActionList     = "list"
+	ActionAdmin    = "admin"
+
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+
+	// Anyone matches every principal, namespace or action
+	Anyone = "*"
+)
+
+type (
+	// Rule allows or denies actions of principals on files, which names have the prefix, in namespaces.
+	// Empty principals, namespaces or actions match any.
+	Rule struct {
+		Principals []string `json:"principals,omitempty"`
+		Namespaces []string `json:"namespaces,omitempty"`
+		Actions    []string `json:"actions,omitempty"`
+		Prefix     string   `json:"prefix,omitempty"`
+		Effect     string   `json:"effect"`
+	}
+
+	// Request is an action, which is authorized. Cluster-wide actions have no namespace.
+	Request struct {
+		Principal string
+		Action    string
+		Namespace string
+		Filename  string
+	}
+
+	// Decision is a result of authorization with the rule, which made it.
+	Decision struct {
+		Allowed bool
+		Rule    int // index of the rule in the policy file, -1 when no rule matches
+	}
+
+	// policyFile is a content of the policy file.
+	policyFile struct {
+		Rules []Rule `json:"rules"`
+	}
+
+	// Policy authorizes requests with rules of the policy file: a request is allowed, when it matches
+	// an allowing rule and doesn't match any denying one.
+	Policy struct {
+		lock    sync.RWMutex
+		path    string
+		modTime time.Time
+		rules   []Rule
+	}
+)
+
+var (
+	actions = []string{ActionUpload, ActionDownload, ActionDelete, ActionList, ActionAdmin}
+)
+
+func LoadPolicy(path string) (*Policy, error) {
+	p := &Policy{
+		path: path,
+	}
+
+	_, err := p.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Reload reads the policy file again, when it was modified since the last successful load, and reports
+// whether the rules were replaced. The policy is not changed, when the file is invalid, and the file
+// is read again by the next check.
+func (p *Policy) Reload() (bool, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return false, err
+	}
+
+	p.lock.RLock()
+	modified := !info.ModTime().Equal(p.modTime)
+	p.lock.RUnlock()
+
+	if !modified {
+		return false, nil
+	}
+
+	b, err := os.ReadFile(p.path)
+	if err != nil {
+		return false, err
+	}
+
+	var f policyFile
+	err = json.Unmarshal(b, &f)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+
+	for i, rule := range f.Rules {
+		err = rule.validate()
+		if err != nil {
+			return false, fmt.Errorf("invalid %d rule: %w", i, err)
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.rules = f.Rules
+	// modification time is kept only for valid policy, so a fixed file is read even with the same time
+	p.modTime = info.ModTime()
+
+	return true, nil
+}
+
+// Authorize evaluates the request against the rules, requests matching no allowing rule are denied.
+func (p *Policy) Authorize(req Request) Decision {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	decision := Decision{Rule: -1}
+	for i, rule := range p.rules {
+		if !rule.matches(req) {
+			continue
+		}
+
+		if rule.Effect == EffectDeny {
+			return Decision{Allowed: false, Rule: i}
+		}
+
+		if !decision.Allowed {
+			decision = Decision{Allowed: true, Rule: i}
+		}
+	}
+
+	return decision
+}
+
+func (r Rule) validate() error {
+	if r.Effect != EffectAllow && r.Effect != EffectDeny {
+		return fmt.Errorf("effect must be %s or %s", EffectAllow, EffectDeny)
+	}
+
+	for _, action := range r.Actions {
+		if action != Anyone && !slices.Contains(actions, action) {
+			return fmt.Errorf("unknown action %q", action)
+		}
+	}
+
+	if slices.Contains(r.Principals, "") || slices.Contains(r.Namespaces, "") {
+		return errors.New("principal and namespace names must not be empty")
+	}
+
+	// file names are a single path segment of API endpoints, such prefix would never match
+	if strings.Contains(r.Prefix, "/") {
+		return errors.New("prefix must not contain /")
+	}
+
+	return nil
+}
+
+func (r Rule) matches(req Request) bool {
+	// rules, limited to namespaces, don't apply to cluster-wide actions
+	if len(req.Namespace) == 0 && len(r.Namespaces) > 0 && !slices.Contains(r.Namespaces, Anyone) {
+		return false
+	}
+
+	return matchAny(r.Principals, req.Principal) &&
+		(len(req.Namespace) == 0 || matchAny(r.Namespaces, req.Namespace)) &&
+		matchAny(r.Actions, req.Action) &&
+		strings.HasPrefix(req.Filename, r.Prefix)
+}
+
+func matchAny(values []string, value string) bool {
+	return len(values) == 0 || slices.Contains(values, Anyone) || slices.Contains(values, value)
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+const testPolicy = `{"rules": [
+	{"principals": ["alice"], "effect": "allow"},
+	{"principals": ["bob"], "namespaces": ["team"], "actions": ["upload", "download"], "effect": "allow"},
+	{"principals": ["*"], "namespaces": ["public"], "actions": ["download", "list"], "effect": "allow"},
+	{"principals": ["alice"], "namespaces": ["team"], "prefix": "secret-", "effect": "deny"},
+	{"actions": ["admin"], "namespaces": ["*"], "principals": ["carol"], "effect": "allow"}
+]}`
+
+func TestPolicyAuthorize(t *testing.T) {
+	policy, err := LoadPolicy(writeFile(t, "policy.json", testPolicy))
+	if err != nil {
+		t.Fatalf("LoadPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  Request
+		want Decision
+	}{
+		{
+			name: "principal allowed everything",
+			req:  Request{Principal: "alice", Action: ActionDelete, Namespace: "team", Filename: "a.txt"},
+			want: Decision{Allowed: true, Rule: 0},
+		},
+		{
+			name: "deny rule takes precedence",
+			req:  Request{Principal: "alice", Action: ActionDownload, Namespace: "team", Filename: "secret-a.txt"},
+			want: Decision{Allowed: false, Rule: 3},
+		},
+		{
+			name: "prefix of deny rule doesn't match",
+			req:  Request{Principal: "alice", Action: ActionDownload, Namespace: "team", Filename: "secrets.txt"},
+			want: Decision{Allowed: true, Rule: 0},
+		},
+		{
+			name: "allowed action in namespace",
+			req:  Request{Principal: "bob", Action: ActionUpload, Namespace: "team", Filename: "a.txt"},
+			want: Decision{Allowed: true, Rule: 1},
+		},
+		{
+			name: "action is not allowed",
+			req:  Request{Principal: "bob", Action: ActionDelete, Namespace: "team", Filename: "a.txt"},
+			want: Decision{Allowed: false, Rule: -1},
+		},
+		{
+			name: "another namespace",
+			req:  Request{Principal: "bob", Action: ActionUpload, Namespace: "default", Filename: "a.txt"},
+			want: Decision{Allowed: false, Rule: -1},
+		},
+		{
+			name: "any principal",
+			req:  Request{Principal: "dave", Action: ActionList, Namespace: "public"},
+			want: Decision{Allowed: true, Rule: 2},
+		},
+		{
+			name: "anonymous principal",
+			req:  Request{Action: ActionDownload, Namespace: "public", Filename: "a.txt"},
+			want: Decision{Allowed: true, Rule: 2},
+		},
+		{
+			name: "namespace rule doesn't apply to cluster-wide action",
+			req:  Request{Principal: "bob", Action: ActionUpload},
+			want: Decision{Allowed: false, Rule: -1},
+		},
+		{
+			name: "any namespace applies to cluster-wide action",
+			req:  Request{Principal: "carol", Action: ActionAdmin},
+			want: Decision{Allowed: true, Rule: 4},
+		},
+		{
+			name: "any namespace",
+			req:  Request{Principal: "carol", Action: ActionAdmin, Namespace: "team"},
+			want: Decision{Allowed: true, Rule: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Authorize(tt.req); got != tt.want {
+				t.Errorf("Authorize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPolicyInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "invalid JSON", content: `{"rules": [`},
+		{name: "unknown effect", content: `{"rules": [{"effect": "permit"}]}`},
+		{name: "unknown action", content: `{"rules": [{"actions": ["read"], "effect": "allow"}]}`},
+		{name: "empty principal", content: `{"rules": [{"principals": [""], "effect": "allow"}]}`},
+		{name: "empty namespace", content: `{"rules": [{"namespaces": [""], "effect": "deny"}]}`},
+		{name: "prefix with slash", content: `{"rules": [{"prefix": "secret/", "effect": "deny"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadPolicy(writeFile(t, "policy.json", tt.content))
+			if err == nil {
+				t.Error("LoadPolicy() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestPolicyReload(t *testing.T) {
+	path := writeFile(t, "policy.json", `{"rules": [{"principals": ["alice"], "effect": "allow"}]}`)
+	start := time.Now().Add(-time.Hour)
+
+	err := os.Chtimes(path, start, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadPolicy() error = %v", err)
+	}
+
+	alice := Request{Principal: "alice", Action: ActionUpload, Namespace: "default"}
+	bob := Request{Principal: "bob", Action: ActionUpload, Namespace: "default"}
+
+	// every step rewrites the policy file, when content is set, and checks the policy after the reload
+	tests := []struct {
+		name         string
+		content      string
+		modTime      time.Time
+		wantReloaded bool
+		wantErr      bool
+		wantAlice    bool
+		wantBob      bool
+	}{
+		{
+			name:      "file is not modified",
+			wantAlice: true,
+		},
+		{
+			name:      "invalid file keeps the rules",
+			content:   `{"rules": [{"effect": "maybe"}]}`,
+			modTime:   start.Add(time.Minute),
+			wantErr:   true,
+			wantAlice: true,
+		},
+		{
+			name:      "invalid file is read again",
+			wantErr:   true,
+			wantAlice: true,
+		},
+		{
+			name:         "fixed file with the same modification time",
+			content:      `{"rules": [{"principals": ["bob"], "effect": "allow"}]}`,
+			modTime:      start.Add(time.Minute),
+			wantReloaded: true,
+			wantBob:      true,
+		},
+		{
+			name:    "reloaded file is not read again",
+			wantBob: true,
+		},
+		{
+			name:         "modified file",
+			content:      `{"rules": [{"principals": ["*"], "effect": "allow"}]}`,
+			modTime:      start.Add(2 * time.Minute),
+			wantReloaded: true,
+			wantAlice:    true,
+			wantBob:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		if len(tt.content) > 0 {
+			err = os.WriteFile(path, []byte(tt.content), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = os.Chtimes(path, tt.modTime, tt.modTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		reloaded, err := policy.Reload()
+		if reloaded != tt.wantReloaded || (err != nil) != tt.wantErr {
+			t.Fatalf("%s: Reload() = %v, %v, want %v, wantErr %v", tt.name, reloaded, err, tt.wantReloaded, tt.wantErr)
+		}
+
+		if got := policy.Authorize(alice).Allowed; got != tt.wantAlice {
+			t.Errorf("%s: alice is allowed = %v, want %v", tt.name, got, tt.wantAlice)
+		}
+
+		if got := policy.Authorize(bob).Allowed; got != tt.wantBob {
+			t.Errorf("%s: bob is allowed = %v, want %v", tt.name, got, tt.wantBob)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+type (
+	// tokenFile is a content of the token file: API tokens by principal name.
+	tokenFile struct {
+		Tokens map[string]string `json:"tokens"`
+	}
+
+	// Tokens authenticates API requests with bearer tokens, every token identifies a principal.
+	Tokens struct {
+		// principals by SHA-256 of their tokens, so a token is not compared byte by byte
+		principals map[[sha256.Size]byte]string
+	}
+)
+
+const (
+	minTokenLength = 16
+)
+
+var (
+	ErrNoTokens = errors.New("no tokens are defined")
+)
+
+func LoadTokens(path string) (*Tokens, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f tokenFile
+	err = json.Unmarshal(b, &f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token file: %w", err)
+	}
+
+	if len(f.Tokens) == 0 {
+		return nil, ErrNoTokens
+	}
+
+	t := &Tokens{
+		principals: make(map[[sha256.Size]byte]string, len(f.Tokens)),
+	}
+	for principal, token := range f.Tokens {
+		if len(principal) == 0 {
+			return nil, errors.New("principal name is empty")
+		}
+
+		if len(token) < minTokenLength {
+			return nil, fmt.Errorf("token of %s must have at least %d characters", principal, minTokenLength)
+		}
+
+		h := sha256.Sum256([]byte(token))
+		if other, ok := t.principals[h]; ok {
+			return nil, fmt.Errorf("%s and %s have the same token", principal, other)
+		}
+
+		t.principals[h] = principal
+	}
+
+	return t, nil
+}
+
+// Authenticate returns the principal, which the token belongs to.
+func (t *Tokens) Authenticate(token string) (string, bool) {
+	principal, ok := t.principals[sha256.Sum256([]byte(token))]
+
+	return principal, ok
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+		errIs   error
+	}{
+		{
+			name:    "valid tokens",
+			content: `{"tokens": {"alice": "0123456789abcdef", "bob": "fedcba9876543210"}}`,
+		},
+		{
+			name:    "no tokens",
+			content: `{"tokens": {}}`,
+			wantErr: true,
+			errIs:   ErrNoTokens,
+		},
+		{
+			name:    "empty file",
+			content: `{}`,
+			wantErr: true,
+			errIs:   ErrNoTokens,
+		},
+		{
+			name:    "short token",
+			content: `{"tokens": {"alice": "0123456789abcde"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "empty principal",
+			content: `{"tokens": {"": "0123456789abcdef"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "same token of two principals",
+			content: `{"tokens": {"alice": "0123456789abcdef", "bob": "0123456789abcdef"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			content: `{"tokens": `,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadTokens(writeFile(t, "tokens.json", tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadTokens() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.errIs != nil && !errors.Is(err, tt.errIs) {
+				t.Errorf("LoadTokens() error = %v, want %v", err, tt.errIs)
+			}
+		})
+	}
+}
+
+func TestTokensAuthenticate(t *testing.T) {
+	tokens, err := LoadTokens(writeFile(t, "tokens.json", `{"tokens": {"alice": "0123456789abcdef", "bob": "fedcba9876543210"}}`))
+	if err != nil {
+		t.Fatalf("LoadTokens() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		wantPrincipal string
+		wantOK        bool
+	}{
+		{name: "first principal", token: "0123456789abcdef", wantPrincipal: "alice", wantOK: true},
+		{name: "second principal", token: "fedcba9876543210", wantPrincipal: "bob", wantOK: true},
+		{name: "empty token", token: ""},
+		{name: "unknown token", token: "0123456789abcdeX"},
+		{name: "token prefix", token: "0123456789abcde"},
+		{name: "principal name", token: "alice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, ok := tokens.Authenticate(tt.token)
+			if principal != tt.wantPrincipal || ok != tt.wantOK {
+				t.Errorf("Authenticate() = %q, %v, want %q, %v", principal, ok, tt.wantPrincipal, tt.wantOK)
+			}
+		})
+	}
+}
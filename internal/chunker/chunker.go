@@ -0,0 +1,124 @@
+package chunker
+
+import (
+	"errors"
+	"math/bits"
+)
+
+const (
+	gearSeed = 0x6b61726d6138 // fixed, so chunk boundaries are the same on every API server
+)
+
+type (
+	// Chunker splits a stream to content-defined chunks using gear rolling hash:
+	// a boundary is found, when masked hash bits are zero, so the same content produces
+	// the same chunks regardless of its position in the stream.
+	Chunker struct {
+		minSize int
+		maxSize int
+		mask    uint64
+
+		buf  []byte
+		pos  int
+		hash uint64
+
+		emit func([]byte) error
+	}
+)
+
+var (
+	gear [256]uint64
+)
+
+func init() {
+	// splitmix64
+	state := uint64(gearSeed)
+	for i := range gear {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
+
+// New creates chunker, which passes chunks between minSize and maxSize bytes to emit.
+// Average chunk size is rounded to a power of two. Chunk passed to emit is valid only until emit returns.
+func New(minSize, avgSize, maxSize int, emit func([]byte) error) (*Chunker, error) {
+	if minSize <= 0 || avgSize < minSize || maxSize < avgSize {
+		return nil, errors.New("invalid chunk sizes")
+	}
+
+	maskBits := bits.Len(uint(avgSize)) - 1
+
+	return &Chunker{
+		minSize: minSize,
+		maxSize: maxSize,
+		mask:    ((1 << maskBits) - 1) << (64 - maskBits),
+		buf:     make([]byte, 0, maxSize),
+		emit:    emit,
+	}, nil
+}
+
+func (c *Chunker) Write(p []byte) error {
+	for len(p) > 0 {
+		n := min(len(p), c.maxSize-len(c.buf))
+		c.buf = append(c.buf, p[:n]...)
+		p = p[n:]
+
+		for {
+			cut := c.boundary()
+			if cut == 0 {
+				break
+			}
+
+			err := c.cut(cut)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Flush emits the rest of the stream as the last chunk.
+func (c *Chunker) Flush() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+
+	return c.cut(len(c.buf))
+}
+
+// boundary returns size of the next chunk or 0, when more data is required.
+func (c *Chunker) boundary() int {
+	// bytes before min size are not hashed, chunks are never smaller than that anyway
+	c.pos = max(c.pos, c.minSize)
+
+	for ; c.pos < len(c.buf); c.pos++ {
+		c.hash = (c.hash << 1) + gear[c.buf[c.pos]]
+		if c.hash&c.mask == 0 {
+			return c.pos + 1
+		}
+	}
+
+	if len(c.buf) >= c.maxSize {
+		return c.maxSize
+	}
+
+	return 0
+}
+
+func (c *Chunker) cut(size int) error {
+	err := c.emit(c.buf[:size])
+	if err != nil {
+		return err
+	}
+
+	c.buf = append(c.buf[:0], c.buf[size:]...)
+	c.pos = 0
+	c.hash = 0
+
+	return nil
+}
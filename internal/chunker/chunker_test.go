@@ -0,0 +1,165 @@
+package chunker
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+const (
+	testMinSize = 64
+	testAvgSize = 256
+	testMaxSize = 1024
+)
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	return data
+}
+
+// split writes data to a new chunker by writeSize bytes and returns the emitted chunks.
+func split(t *testing.T, data []byte, writeSize int) [][]byte {
+	t.Helper()
+
+	var chunks [][]byte
+	c, err := New(testMinSize, testAvgSize, testMaxSize, func(chunk []byte) error {
+		chunks = append(chunks, append([]byte(nil), chunk...))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for p := data; len(p) > 0; {
+		n := min(len(p), writeSize)
+		err = c.Write(p[:n])
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		p = p[n:]
+	}
+
+	err = c.Flush()
+	if err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	return chunks
+}
+
+func TestChunker(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		writeSize int
+	}{
+		{
+			name:      "empty stream",
+			data:      nil,
+			writeSize: 1,
+		},
+		{
+			name:      "stream smaller than min size",
+			data:      testData(testMinSize - 1),
+			writeSize: 1,
+		},
+		{
+			name:      "random data in single write",
+			data:      testData(64 << 10),
+			writeSize: 64 << 10,
+		},
+		{
+			name:      "random data in small writes",
+			data:      testData(64 << 10),
+			writeSize: 7,
+		},
+		{
+			name:      "random data in writes larger than max size",
+			data:      testData(64 << 10),
+			writeSize: 3*testMaxSize + 1,
+		},
+		{
+			name:      "zeros are cut at max size",
+			data:      make([]byte, 4*testMaxSize+10),
+			writeSize: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := split(t, tt.data, tt.writeSize)
+
+			if got := bytes.Join(chunks, nil); !bytes.Equal(got, tt.data) {
+				t.Fatalf("chunks make %d bytes, want %d bytes of the stream", len(got), len(tt.data))
+			}
+
+			for i, chunk := range chunks {
+				if len(chunk) > testMaxSize {
+					t.Errorf("%d chunk has %d bytes, more than max size", i, len(chunk))
+				}
+
+				// only the last chunk could be smaller than min size
+				if i < len(chunks)-1 && len(chunk) < testMinSize {
+					t.Errorf("%d chunk has %d bytes, less than min size", i, len(chunk))
+				}
+
+				if len(chunk) == 0 {
+					t.Errorf("%d chunk is empty", i)
+				}
+			}
+
+			// boundaries depend only on content, not on how it is written
+			if want := split(t, tt.data, len(tt.data)+1); !reflect.DeepEqual(chunks, want) {
+				t.Errorf("got %d chunks, want %d chunks of the single write", len(chunks), len(want))
+			}
+		})
+	}
+}
+
+func TestChunkerShiftedContent(t *testing.T) {
+	data := testData(64 << 10)
+	shifted := append(testData(100)[:37], data...)
+
+	original := split(t, data, len(data))
+	moved := split(t, shifted, len(shifted))
+
+	// chunks after the first boundary are found again, when content is shifted
+	seen := make(map[string]bool, len(original))
+	for _, chunk := range original {
+		seen[string(chunk)] = true
+	}
+
+	found := 0
+	for _, chunk := range moved {
+		if seen[string(chunk)] {
+			found++
+		}
+	}
+
+	if found < len(original)-2 {
+		t.Errorf("%d of %d chunks are found in shifted content", found, len(original))
+	}
+}
+
+func TestNewInvalidSizes(t *testing.T) {
+	tests := []struct {
+		name                      string
+		minSize, avgSize, maxSize int
+	}{
+		{name: "zero min size", minSize: 0, avgSize: 256, maxSize: 1024},
+		{name: "avg size less than min size", minSize: 256, avgSize: 64, maxSize: 1024},
+		{name: "max size less than avg size", minSize: 64, avgSize: 256, maxSize: 128},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.minSize, tt.avgSize, tt.maxSize, func([]byte) error { return nil })
+			if err == nil {
+				t.Error("New() error = nil, want error")
+			}
+		})
+	}
+}
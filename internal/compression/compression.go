@@ -0,0 +1,88 @@
+package compression
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type (
+	// Codec is a name of compression algorithm, empty one means no compression.
+	Codec = string
+)
+
+const (
+	None Codec = "none"
+	Gzip Codec = "gzip"
+	Zstd Codec = "zstd"
+)
+
+// Parse validates codec name, empty name is the default codec.
+func Parse(name string, defaultCodec Codec) (Codec, error) {
+	switch name {
+	case "":
+		return defaultCodec, nil
+	case None, Gzip, Zstd:
+		return name, nil
+	default:
+		return "", fmt.Errorf("unknown compression codec %q", name)
+	}
+}
+
+// Compressed reports whether data is modified by the codec.
+func Compressed(codec Codec) bool {
+	return codec != "" && codec != None
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// NewWriter returns writer, which compresses data to w. Close must be called to flush compressed data,
+// it doesn't close w.
+func NewWriter(codec Codec, w io.Writer) (io.WriteCloser, error) {
+	switch codec {
+	case "", None:
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	default:
+		return nil, fmt.Errorf("unknown compression codec %q", codec)
+	}
+}
+
+type zstdReadCloser struct {
+	*zstd.Decoder
+}
+
+func (z zstdReadCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
+
+// NewReader returns reader of data decompressed from r. Close releases decoder resources, it doesn't close r.
+func NewReader(codec Codec, r io.Reader) (io.ReadCloser, error) {
+	switch codec {
+	case "", None:
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+
+		return zstdReadCloser{d}, nil
+	default:
+		return nil, fmt.Errorf("unknown compression codec %q", codec)
+	}
+}
@@ -0,0 +1,146 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func compress(t *testing.T, codec Codec, data []byte) []byte {
+	t.Helper()
+
+	var compressed bytes.Buffer
+	w, err := NewWriter(codec, &compressed)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	return compressed.Bytes()
+}
+
+func decompress(codec Codec, compressed []byte) ([]byte, error) {
+	r, err := NewReader(codec, bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		codec   string
+		want    Codec
+		wantErr bool
+	}{
+		{name: "default codec", codec: "", want: Zstd},
+		{name: "no compression", codec: None, want: None},
+		{name: "gzip", codec: Gzip, want: Gzip},
+		{name: "zstd", codec: Zstd, want: Zstd},
+		{name: "unknown codec", codec: "lz4", wantErr: true},
+		{name: "codec in upper case", codec: "GZIP", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.codec, Zstd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	random := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	inputs := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: []byte{}},
+		{name: "single byte", data: []byte{1}},
+		{name: "text", data: bytes.Repeat([]byte("compressible text "), 10000)},
+		{name: "random", data: random},
+	}
+
+	for _, codec := range []Codec{"", None, Gzip, Zstd} {
+		for _, input := range inputs {
+			t.Run(codec+"/"+input.name, func(t *testing.T) {
+				compressed := compress(t, codec, input.data)
+
+				if !Compressed(codec) && !bytes.Equal(compressed, input.data) {
+					t.Errorf("data is modified without compression")
+				}
+
+				if Compressed(codec) && input.name == "text" && len(compressed) >= len(input.data)/10 {
+					t.Errorf("%d bytes of text are compressed to %d bytes", len(input.data), len(compressed))
+				}
+
+				got, err := decompress(codec, compressed)
+				if err != nil {
+					t.Fatalf("decompress() error = %v", err)
+				}
+
+				if !bytes.Equal(got, input.data) {
+					t.Errorf("decompressed %d bytes, which differ from %d original bytes", len(got), len(input.data))
+				}
+			})
+		}
+	}
+}
+
+func TestUnknownCodec(t *testing.T) {
+	_, err := NewWriter("lz4", io.Discard)
+	if err == nil {
+		t.Error("NewWriter() of unknown codec succeeded")
+	}
+
+	_, err = NewReader("lz4", bytes.NewReader(nil))
+	if err == nil {
+		t.Error("NewReader() of unknown codec succeeded")
+	}
+}
+
+func TestDamagedData(t *testing.T) {
+	data := bytes.Repeat([]byte("compressible text "), 10000)
+
+	for _, codec := range []Codec{Gzip, Zstd} {
+		compressed := compress(t, codec, data)
+
+		tests := []struct {
+			name       string
+			compressed []byte
+		}{
+			{name: "not compressed", compressed: data},
+			{name: "truncated", compressed: compressed[:len(compressed)/2]},
+		}
+
+		for _, tt := range tests {
+			t.Run(codec+"/"+tt.name, func(t *testing.T) {
+				got, err := decompress(codec, tt.compressed)
+				if err == nil {
+					t.Errorf("decompress() = %d bytes, want error", len(got))
+				}
+			})
+		}
+	}
+}
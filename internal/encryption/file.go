@@ -0,0 +1,135 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	// FileHeaderSize is a size of end-to-end encrypted file header: magic, version, salt and key check.
+	FileHeaderSize = len(fileMagic) + 1 + fileSaltSize + fileKeyCheckSize
+
+	fileMagic        = "K8TE"
+	fileVersion      = 1
+	fileSaltSize     = 16
+	fileKeyCheckSize = 16
+)
+
+var (
+	ErrWrongKey     = errors.New("file is encrypted with another key")
+	ErrNotEncrypted = errors.New("file is not encrypted")
+)
+
+// LoadKey reads base64 encoded 32-byte key from the file.
+func LoadKey(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(b)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key: %w", err)
+	}
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must have %d bytes", KeySize)
+	}
+
+	return key, nil
+}
+
+// NewFileHeader generates header of a new end-to-end encrypted file and returns cipher of its segments.
+// Every file has own segment key derived from the key and random salt.
+func NewFileHeader(key []byte) ([]byte, *SegmentCipher, error) {
+	salt := make([]byte, fileSaltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := make([]byte, 0, FileHeaderSize)
+	header = append(header, fileMagic...)
+	header = append(header, fileVersion)
+	header = append(header, salt...)
+	header = append(header, derive(key, "check", salt)[:fileKeyCheckSize]...)
+
+	c, err := fileCipher(key, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return header, c, nil
+}
+
+// ParseFileHeader checks that the file is encrypted with the key and returns cipher of its segments.
+func ParseFileHeader(key, header []byte) (*SegmentCipher, error) {
+	if len(header) != FileHeaderSize || string(header[:len(fileMagic)]) != fileMagic {
+		return nil, ErrNotEncrypted
+	}
+
+	header = header[len(fileMagic):]
+	if header[0] != fileVersion {
+		return nil, fmt.Errorf("unsupported encrypted file version %d", header[0])
+	}
+
+	salt, check := header[1:1+fileSaltSize], header[1+fileSaltSize:]
+	if !hmac.Equal(check, derive(key, "check", salt)[:fileKeyCheckSize]) {
+		return nil, ErrWrongKey
+	}
+
+	return fileCipher(key, salt)
+}
+
+// FileSegments returns number of segments of end-to-end encrypted file, there is at least one segment.
+func FileSegments(size int64) int64 {
+	return max(1, (size+SegmentSize-1)/SegmentSize)
+}
+
+// EncryptedFileSize returns size of end-to-end encrypted file of the plaintext size.
+func EncryptedFileSize(size int64) int64 {
+	return int64(FileHeaderSize) + size + FileSegments(size)*Overhead
+}
+
+// PlainFileSize returns plaintext size of end-to-end encrypted file of the size.
+func PlainFileSize(size int64) (int64, error) {
+	size -= int64(FileHeaderSize)
+	segments := (size + SegmentSize + Overhead - 1) / (SegmentSize + Overhead)
+	if size < Overhead || size-segments*Overhead < 0 {
+		return 0, ErrTruncated
+	}
+
+	return size - segments*Overhead, nil
+}
+
+// SegmentOffset returns offset of the segment in end-to-end encrypted file.
+func SegmentOffset(index int64) int64 {
+	return int64(FileHeaderSize) + index*(SegmentSize+Overhead)
+}
+
+func fileCipher(key, salt []byte) (*SegmentCipher, error) {
+	aead, err := newAEAD(derive(key, "data", salt))
+	if err != nil {
+		return nil, err
+	}
+
+	// segment key is unique per file, so nonce prefix is not needed
+	return &SegmentCipher{
+		aead:   aead,
+		prefix: make([]byte, noncePrefixSize),
+	}, nil
+}
+
+func derive(key []byte, purpose string, salt []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	mac.Write(salt)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,73 @@
+package encryption
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFileSizes(t *testing.T) {
+	tests := []struct {
+		name string
+		size int64
+	}{
+		{name: "empty file", size: 0},
+		{name: "one byte", size: 1},
+		{name: "exactly one segment", size: SegmentSize},
+		{name: "segment and one byte", size: SegmentSize + 1},
+		{name: "several segments", size: 5*SegmentSize + 123},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PlainFileSize(EncryptedFileSize(tt.size))
+			if err != nil {
+				t.Fatalf("PlainFileSize() error = %v", err)
+			}
+
+			if got != tt.size {
+				t.Errorf("PlainFileSize() = %d, want %d", got, tt.size)
+			}
+		})
+	}
+}
+
+func TestParseFileHeader(t *testing.T) {
+	key := testKey(1)
+
+	header, c, err := NewFileHeader(key)
+	if err != nil {
+		t.Fatalf("NewFileHeader() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     []byte
+		header  []byte
+		wantErr error
+	}{
+		{name: "same key", key: key, header: header},
+		{name: "another key", key: testKey(2), header: header, wantErr: ErrWrongKey},
+		{name: "truncated header", key: key, header: header[:FileHeaderSize-1], wantErr: ErrNotEncrypted},
+		{name: "plain file", key: key, header: make([]byte, FileHeaderSize), wantErr: ErrNotEncrypted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseFileHeader(tt.key, tt.header)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseFileHeader() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			// segments sealed by the uploading client are opened with the parsed header
+			sealed := c.Seal(nil, []byte("segment"), 2, true)
+			_, err = parsed.Open(nil, sealed, 2, true)
+			if err != nil {
+				t.Errorf("Open() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	KeySize = 32 // AES-256
+)
+
+type (
+	// WrappedKey is a data key encrypted with the master key.
+	WrappedKey struct {
+		KeyID string `json:"key_id"`
+		Key   []byte `json:"key"` // nonce followed by sealed data key
+	}
+
+	// keyFile is a content of the master key file, keys are base64 encoded.
+	keyFile struct {
+		Current string            `json:"current"`
+		Keys    map[string]string `json:"keys"`
+	}
+
+	// Keyring keeps master keys, data keys are wrapped with the current one. Keys, which
+	// are not current, are used only to unwrap data keys until they are rotated.
+	Keyring struct {
+		lock    sync.RWMutex
+		path    string
+		current string
+		keys    map[string]cipher.AEAD
+	}
+)
+
+var (
+	ErrUnknownKey = errors.New("master key is not found")
+)
+
+func LoadKeyring(path string) (*Keyring, error) {
+	k := &Keyring{
+		path: path,
+	}
+
+	err := k.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Reload reads the key file again, so keys could be rotated without restart.
+// The keyring is not changed, when the file is invalid.
+func (k *Keyring) Reload() error {
+	b, err := os.ReadFile(k.path)
+	if err != nil {
+		return err
+	}
+
+	var f keyFile
+	err = json.Unmarshal(b, &f)
+	if err != nil {
+		return fmt.Errorf("failed to parse key file: %w", err)
+	}
+
+	keys := make(map[string]cipher.AEAD, len(f.Keys))
+	for id, encoded := range f.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s key: %w", id, err)
+		}
+
+		if len(key) != KeySize {
+			return fmt.Errorf("%s key must have %d bytes", id, KeySize)
+		}
+
+		keys[id], err = newAEAD(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, ok := keys[f.Current]; !ok {
+		return fmt.Errorf("current key %q: %w", f.Current, ErrUnknownKey)
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.current = f.Current
+	k.keys = keys
+
+	return nil
+}
+
+// Current returns ID of the key, which wraps new data keys.
+func (k *Keyring) Current() string {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	return k.current
+}
+
+// NewDataKey generates random data key and returns it along with its wrapped form.
+func (k *Keyring) NewDataKey() ([]byte, *WrappedKey, error) {
+	dataKey := make([]byte, KeySize)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	wrapped, err := wrap(k.keys[k.current], k.current, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dataKey, wrapped, nil
+}
+
+func (k *Keyring) Unwrap(w *WrappedKey) ([]byte, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	return k.unwrap(w)
+}
+
+// Rewrap wraps data key with the current master key, it reports whether the key was wrapped with another one.
+func (k *Keyring) Rewrap(w *WrappedKey) (*WrappedKey, bool, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	if w.KeyID == k.current {
+		return w, false, nil
+	}
+
+	dataKey, err := k.unwrap(w)
+	if err != nil {
+		return nil, false, err
+	}
+
+	rewrapped, err := wrap(k.keys[k.current], k.current, dataKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return rewrapped, true, nil
+}
+
+func (k *Keyring) unwrap(w *WrappedKey) ([]byte, error) {
+	aead, ok := k.keys[w.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%s key: %w", w.KeyID, ErrUnknownKey)
+	}
+
+	nonceSize := aead.NonceSize()
+	if len(w.Key) < nonceSize {
+		return nil, errors.New("wrapped key is too short")
+	}
+
+	return aead.Open(nil, w.Key[:nonceSize], w.Key[nonceSize:], []byte(w.KeyID))
+}
+
+func wrap(aead cipher.AEAD, keyID string, dataKey []byte) (*WrappedKey, error) {
+	nonce := make([]byte, aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WrappedKey{
+		KeyID: keyID,
+		Key:   aead.Seal(nonce, nonce, dataKey, []byte(keyID)),
+	}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
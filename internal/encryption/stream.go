@@ -0,0 +1,221 @@
+package encryption
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	// SegmentSize is a size of plaintext sealed at once, so data is streamed without buffering the whole fragment.
+	SegmentSize = 64 << 10
+
+	// stream starts with random nonce prefix, segment nonce is the prefix, segment counter and final flag
+	noncePrefixSize = 7
+
+	// Overhead is a size of authentication tag of every segment.
+	Overhead = 16
+)
+
+type (
+	// SegmentCipher seals segments of a stream independently, so the stream could be read from any segment.
+	// Segment nonce consists of the prefix, segment index and final flag, so segments can't be reordered
+	// and the stream can't be truncated.
+	SegmentCipher struct {
+		aead   cipher.AEAD
+		prefix []byte
+		ad     []byte
+	}
+
+	writer struct {
+		cipher  *SegmentCipher
+		w       io.Writer
+		counter uint32
+		buf     []byte
+		out     []byte
+	}
+
+	reader struct {
+		cipher  *SegmentCipher
+		r       *bufio.Reader
+		counter uint32
+		segment []byte
+		plain   []byte
+		done    bool
+	}
+)
+
+var (
+	ErrDecrypt   = errors.New("failed to decrypt data, key is wrong or data is damaged")
+	ErrTruncated = errors.New("encrypted data is truncated")
+)
+
+// NewWriter returns writer, which encrypts data to w with the data key. The fragment number is authenticated,
+// so fragments can't be swapped. Close must be called to seal the last segment, it doesn't close w.
+func NewWriter(dataKey []byte, fragment int, w io.Writer) (io.WriteCloser, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	_, err = rand.Read(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.Write(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writer{
+		cipher: &SegmentCipher{
+			aead:   aead,
+			prefix: prefix,
+			ad:     binary.BigEndian.AppendUint32(nil, uint32(fragment)),
+		},
+		w:   w,
+		buf: make([]byte, 0, SegmentSize),
+		out: make([]byte, 0, SegmentSize+Overhead),
+	}, nil
+}
+
+func (sw *writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// full segment is sealed only when more data follows, the last one is sealed by Close
+		if len(sw.buf) == SegmentSize {
+			err := sw.seal(false)
+			if err != nil {
+				return written, err
+			}
+		}
+
+		n := min(len(p), SegmentSize-len(sw.buf))
+		sw.buf = append(sw.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+func (sw *writer) Close() error {
+	return sw.seal(true)
+}
+
+func (sw *writer) seal(final bool) error {
+	sw.out = sw.cipher.Seal(sw.out[:0], sw.buf, sw.counter, final)
+	sw.counter++
+	sw.buf = sw.buf[:0]
+
+	_, err := sw.w.Write(sw.out)
+
+	return err
+}
+
+// NewReader returns reader of data decrypted from r with the data key.
+func NewReader(dataKey []byte, fragment int, r io.Reader) (io.Reader, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	_, err = io.ReadFull(r, prefix)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, ErrTruncated
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &reader{
+		cipher: &SegmentCipher{
+			aead:   aead,
+			prefix: prefix,
+			ad:     binary.BigEndian.AppendUint32(nil, uint32(fragment)),
+		},
+		r:       bufio.NewReader(r),
+		segment: make([]byte, SegmentSize+Overhead),
+	}, nil
+}
+
+func (sr *reader) Read(p []byte) (int, error) {
+	for len(sr.plain) == 0 {
+		if sr.done {
+			return 0, io.EOF
+		}
+
+		err := sr.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, sr.plain)
+	sr.plain = sr.plain[n:]
+
+	return n, nil
+}
+
+func (sr *reader) open() error {
+	n, err := io.ReadFull(sr.r, sr.segment)
+
+	final := false
+	switch {
+	case errors.Is(err, io.EOF):
+		return ErrTruncated
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		final = true
+	case err != nil:
+		return err
+	default:
+		_, err = sr.r.Peek(1)
+		if errors.Is(err, io.EOF) {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	sr.plain, err = sr.cipher.Open(sr.segment[:0], sr.segment[:n], sr.counter, final)
+	if err != nil {
+		return err
+	}
+
+	sr.counter++
+	sr.done = final
+
+	return nil
+}
+
+// Seal appends sealed segment with the index to dst.
+func (c *SegmentCipher) Seal(dst, plain []byte, index uint32, final bool) []byte {
+	return c.aead.Seal(dst, nonce(c.prefix, index, final), plain, c.ad)
+}
+
+// Open appends opened segment with the index to dst.
+func (c *SegmentCipher) Open(dst, sealed []byte, index uint32, final bool) ([]byte, error) {
+	plain, err := c.aead.Open(dst, nonce(c.prefix, index, final), sealed, c.ad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plain, nil
+}
+
+func nonce(prefix []byte, counter uint32, final bool) []byte {
+	n := make([]byte, 0, noncePrefixSize+5)
+	n = append(n, prefix...)
+	n = binary.BigEndian.AppendUint32(n, counter)
+	if final {
+		return append(n, 1)
+	}
+
+	return append(n, 0)
+}
@@ -0,0 +1,205 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+const (
+	// every segment is sealed with AES-GCM tag
+	tagSize           = 16
+	sealedSegmentSize = SegmentSize + tagSize
+)
+
+func testKey(seed int64) []byte {
+	key := make([]byte, KeySize)
+	rand.New(rand.NewSource(seed)).Read(key)
+
+	return key
+}
+
+func testPlaintext(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+
+	return data
+}
+
+func encrypt(t *testing.T, key []byte, fragment int, plain []byte, writeSize int) []byte {
+	t.Helper()
+
+	var sealed bytes.Buffer
+	w, err := NewWriter(key, fragment, &sealed)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	for p := plain; len(p) > 0; {
+		n := min(len(p), writeSize)
+		_, err = w.Write(p[:n])
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		p = p[n:]
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	return sealed.Bytes()
+}
+
+func decrypt(key []byte, fragment int, sealed []byte) ([]byte, error) {
+	r, err := NewReader(key, fragment, bytes.NewReader(sealed))
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		writeSize int
+	}{
+		{name: "empty fragment", size: 0, writeSize: 1},
+		{name: "smaller than segment", size: 1000, writeSize: 1000},
+		{name: "exactly one segment", size: SegmentSize, writeSize: SegmentSize},
+		{name: "segment and one byte", size: SegmentSize + 1, writeSize: 4096},
+		{name: "several segments in small writes", size: 3*SegmentSize + 100, writeSize: 1000},
+		{name: "several segments in single write", size: 3 * SegmentSize, writeSize: 3 * SegmentSize},
+	}
+
+	key := testKey(1)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := testPlaintext(tt.size)
+			sealed := encrypt(t, key, 3, plain, tt.writeSize)
+
+			segments := max(1, (tt.size+SegmentSize-1)/SegmentSize)
+			if want := noncePrefixSize + tt.size + segments*tagSize; len(sealed) != want {
+				t.Errorf("sealed size = %d, want %d", len(sealed), want)
+			}
+
+			got, err := decrypt(key, 3, sealed)
+			if err != nil {
+				t.Fatalf("decrypt() error = %v", err)
+			}
+
+			if !bytes.Equal(got, plain) {
+				t.Errorf("decrypted %d bytes, which differ from %d plaintext bytes", len(got), len(plain))
+			}
+		})
+	}
+}
+
+func TestStreamDamaged(t *testing.T) {
+	key := testKey(1)
+	plain := testPlaintext(3*SegmentSize + 100)
+
+	segment := func(sealed []byte, i int) []byte {
+		start := noncePrefixSize + i*sealedSegmentSize
+		return sealed[start:min(len(sealed), start+sealedSegmentSize)]
+	}
+
+	tests := []struct {
+		name     string
+		key      []byte
+		fragment int
+		damage   func(sealed []byte) []byte
+		wantErr  error
+	}{
+		{
+			name:     "truncated nonce prefix",
+			key:      key,
+			fragment: 3,
+			damage:   func(sealed []byte) []byte { return sealed[:noncePrefixSize-1] },
+			wantErr:  ErrTruncated,
+		},
+		{
+			name:     "all segments are cut off",
+			key:      key,
+			fragment: 3,
+			damage:   func(sealed []byte) []byte { return sealed[:noncePrefixSize] },
+			wantErr:  ErrTruncated,
+		},
+		{
+			name:     "last segment is cut off",
+			key:      key,
+			fragment: 3,
+			damage:   func(sealed []byte) []byte { return sealed[:noncePrefixSize+3*sealedSegmentSize] },
+			wantErr:  ErrDecrypt,
+		},
+		{
+			name:     "last segment is cut",
+			key:      key,
+			fragment: 3,
+			damage:   func(sealed []byte) []byte { return sealed[:len(sealed)-1] },
+			wantErr:  ErrDecrypt,
+		},
+		{
+			name:     "segments are reordered",
+			key:      key,
+			fragment: 3,
+			damage: func(sealed []byte) []byte {
+				first := bytes.Clone(segment(sealed, 0))
+				copy(segment(sealed, 0), segment(sealed, 1))
+				copy(segment(sealed, 1), first)
+				return sealed
+			},
+			wantErr: ErrDecrypt,
+		},
+		{
+			name:     "segment is dropped",
+			key:      key,
+			fragment: 3,
+			damage: func(sealed []byte) []byte {
+				return append(sealed[:noncePrefixSize+sealedSegmentSize:noncePrefixSize+sealedSegmentSize], sealed[noncePrefixSize+2*sealedSegmentSize:]...)
+			},
+			wantErr: ErrDecrypt,
+		},
+		{
+			name:     "byte is flipped",
+			key:      key,
+			fragment: 3,
+			damage: func(sealed []byte) []byte {
+				sealed[noncePrefixSize+sealedSegmentSize+10] ^= 1
+				return sealed
+			},
+			wantErr: ErrDecrypt,
+		},
+		{
+			name:     "another fragment number",
+			key:      key,
+			fragment: 4,
+			damage:   func(sealed []byte) []byte { return sealed },
+			wantErr:  ErrDecrypt,
+		},
+		{
+			name:     "another key",
+			key:      testKey(2),
+			fragment: 3,
+			damage:   func(sealed []byte) []byte { return sealed },
+			wantErr:  ErrDecrypt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed := tt.damage(encrypt(t, key, 3, plain, len(plain)))
+
+			_, err := decrypt(tt.key, tt.fragment, sealed)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("decrypt() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
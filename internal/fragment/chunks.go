@@ -0,0 +1,173 @@
+package fragment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aburluka/k8test/internal/compression"
+	"github.com/aburluka/k8test/internal/encryption"
+)
+
+type (
+	// Chunk is a content-defined part of deduplicated files. It is stored once per cluster
+	// and deleted when no file references it.
+	Chunk struct {
+		Key      string                 `json:"key,omitempty"` // name of the stored chunk, ChunkKey when empty
+		Address  string                 `json:"address"`
+		Size     int64                  `json:"size"`
+		Codec    string                 `json:"codec,omitempty"`    // compression codec of the stored chunk
+		DataKey  *encryption.WrappedKey `json:"data_key,omitempty"` // key of encrypted chunk
+		Refs     int                    `json:"refs"`
+		Deleting bool                   `json:"deleting,omitempty"`
+	}
+
+	// ChunkRef is an entry of the file manifest.
+	ChunkRef struct {
+		Hash    string                 `json:"hash"` // SHA-256 of the chunk
+		Key     string                 `json:"key,omitempty"`
+		Address string                 `json:"address"`
+		Size    int64                  `json:"size"`
+		Codec   string                 `json:"codec,omitempty"`
+		DataKey *encryption.WrappedKey `json:"data_key,omitempty"`
+	}
+)
+
+const (
+	chunkKeyPrefix = "chunk-"
+)
+
+var (
+	ErrChunkDeleting = errors.New("chunk is being deleted")
+)
+
+// ChunkKey returns the name chunk is stored with on bucket servers. Compressed chunks are stored
+// with the codec in the name, so chunks compressed differently never clash.
+func ChunkKey(hash, codec string) string {
+	if !compression.Compressed(codec) {
+		return chunkKeyPrefix + hash
+	}
+
+	return chunkKeyPrefix + hash + "." + codec
+}
+
+// StorageKey returns the name chunk is stored with on bucket servers.
+func (ref ChunkRef) StorageKey() string {
+	if len(ref.Key) == 0 {
+		return ChunkKey(ref.Hash, ref.Codec)
+	}
+
+	return ref.Key
+}
+
+// RefChunk adds already stored chunk to the upload manifest and reports whether the chunk was found.
+// Chunk, which is being deleted, can't be referenced until it is forgotten.
+func (r *Registry) RefChunk(uploadID, hash string, hashState []byte) (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	c, ok := r.Chunks[hash]
+	if !ok {
+		return false, nil
+	}
+
+	if c.Deleting {
+		return false, ErrChunkDeleting
+	}
+
+	return true, r.appendChunk(uploadID, hash, c, hashState)
+}
+
+// AddChunk records chunk, stored by the upload, and adds it to the upload manifest. When the same chunk was
+// recorded concurrently, the recorded one is referenced instead and returned.
+func (r *Registry) AddChunk(uploadID, hash string, chunk Chunk, hashState []byte) (Chunk, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	c, ok := r.Chunks[hash]
+	if ok && c.Deleting {
+		return Chunk{}, ErrChunkDeleting
+	}
+
+	if !ok {
+		chunk.Refs = 0
+		chunk.Deleting = false
+
+		c = &chunk
+		r.Chunks[hash] = c
+	}
+
+	return *c, r.appendChunk(uploadID, hash, c, hashState)
+}
+
+func (r *Registry) appendChunk(uploadID, hash string, c *Chunk, hashState []byte) error {
+	fm, ok := r.Uploads[uploadID]
+	if !ok {
+		return fmt.Errorf("no upload with %s ID", uploadID)
+	}
+
+	c.Refs++
+	fm.Manifest = append(fm.Manifest, ChunkRef{
+		Hash:    hash,
+		Key:     c.Key,
+		Address: c.Address,
+		Size:    c.Size,
+		Codec:   c.Codec,
+		DataKey: c.DataKey,
+	})
+	fm.HashState = hashState
+	fm.UpdatedAt = time.Now().UTC()
+
+	return r.store()
+}
+
+// unref releases chunks of the file manifest, chunks without references are queued for deletion.
+func (r *Registry) unref(fm *FileMeta) {
+	for _, ref := range fm.Manifest {
+		c, ok := r.Chunks[ref.Hash]
+		if !ok {
+			continue
+		}
+
+		c.Refs--
+		if c.Refs <= 0 {
+			c.Deleting = true
+		}
+	}
+}
+
+// DeletingChunks returns chunks, which are not referenced anymore and can be deleted.
+func (r *Registry) DeletingChunks() []ChunkRef {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var chunks []ChunkRef
+	for hash, c := range r.Chunks {
+		if c.Deleting {
+			chunks = append(chunks, ChunkRef{
+				Hash:    hash,
+				Key:     c.Key,
+				Address: c.Address,
+				Size:    c.Size,
+				Codec:   c.Codec,
+			})
+		}
+	}
+
+	return chunks
+}
+
+// ForgetChunk removes deleted chunk, so it could be stored again.
+func (r *Registry) ForgetChunk(hash string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	c, ok := r.Chunks[hash]
+	if !ok || !c.Deleting {
+		return nil
+	}
+
+	delete(r.Chunks, hash)
+
+	return r.store()
+}
@@ -1,24 +1,100 @@
 package fragment
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/aburluka/k8test/internal/encryption"
 )
 
 type (
 	UploadStatus = int
 
 	FileMeta struct {
-		Status    UploadStatus `json:"status"`
-		Name      string       `json:"filename"`
-		Addresses []string     `json:"addresses"` // index is fragment number
+		Status      UploadStatus           `json:"status"`
+		Namespace   string                 `json:"namespace"`
+		Name        string                 `json:"filename"`
+		UploadID    string                 `json:"upload_id,omitempty"`
+		Size        int64                  `json:"size"`
+		Checksum    string                 `json:"checksum,omitempty"` // SHA-256 of the whole file
+		CreatedAt   time.Time              `json:"created_at"`
+		CompletedAt time.Time              `json:"completed_at"`
+		UpdatedAt   time.Time              `json:"updated_at"`
+		HashState   []byte                 `json:"hash_state,omitempty"`   // checksum state of committed fragments, to resume upload
+		Exclusive   bool                   `json:"exclusive,omitempty"`    // upload must not replace existing file
+		Codec       string                 `json:"codec,omitempty"`        // compression codec of stored fragments
+		DataKey     *encryption.WrappedKey `json:"data_key,omitempty"`     // key of encrypted fragments
+		ContentType string                 `json:"content_type,omitempty"` // media type, sent with downloads
+		Metadata    map[string]string      `json:"metadata,omitempty"`     // user metadata, sent with downloads
+		Replication int                    `json:"replication,omitempty"`  // bucket servers storing every fragment
+		Fragments   []Fragment             `json:"fragments"`              // index is fragment number
+		Manifest    []ChunkRef             `json:"manifest,omitempty"`     // chunks of deduplicated file in order
+
+		// fragments of registries, created before the fragment table was introduced, converted on load
+		LegacyAddresses []string `json:"addresses,omitempty"`
+		LegacySizes     []int64  `json:"sizes,omitempty"`
+		LegacyChecksums []string `json:"checksums,omitempty"`
+	}
+
+	// Fragment is a record of the file fragment table.
+	Fragment struct {
+		Index    int      `json:"index"`
+		Offset   int64    `json:"offset"`             // offset of the fragment in the file, -1 when not recorded
+		Length   int64    `json:"length"`             // -1 when not recorded
+		Checksum string   `json:"checksum,omitempty"` // SHA-256 of the fragment
+		Replicas []string `json:"replicas"`           // addresses of bucket servers, which store the fragment
+	}
+
+	// UploadOptions are requirements to the upload, which are checked when upload is complete.
+	UploadOptions struct {
+		Exclusive   bool                   // fail the upload, if file with the same name exists
+		Checksum    string                 // expected SHA-256 of the file
+		Codec       string                 // compression codec of stored fragments
+		DataKey     *encryption.WrappedKey // key to encrypt fragments with
+		ContentType string                 // media type of the file
+		Metadata    map[string]string      // user metadata
+	}
+
+	// FragmentRange is a part of the fragment, which is required to read a byte range of the file.
+	FragmentRange struct {
+		Key      string
+		Codec    string
+		DataKey  *encryption.WrappedKey
+		Fragment int
+		Address  string
+		Replicas []string // addresses of all bucket servers storing the fragment, the first one is Address
+		Offset   int64
+		Length   int64
 	}
 
 	Registry struct {
-		lock  sync.Mutex
-		Files map[string]*FileMeta `json:"files"`
+		lock sync.Mutex
+		// Namespaces keeps committed files by namespace and name.
+		Namespaces map[string]*Namespace `json:"namespaces"`
+
+		Uploads map[string]*FileMeta `json:"uploads"`
+		// Garbage keeps replaced and deleted files, which fragments are waiting for deletion.
+		Garbage []*FileMeta `json:"garbage"`
+		// Chunks keeps chunks of deduplicated files by hash.
+		Chunks map[string]*Chunk `json:"chunks"`
+
+		// replaced fragments are not deleted while they are downloaded
+		readers map[string]int
+		// sessions keeps IDs of uploads, which are being received right now.
+		sessions map[string]bool
+
+		// committed files of registries, created before namespaces were introduced, moved to the default namespace on load
+		LegacyFiles map[string]*FileMeta `json:"files,omitempty"`
 	}
 )
 
@@ -28,24 +104,323 @@ const (
 	UploadStatusIncomplete UploadStatus = iota
 	UploadStatusComplete
 	UploadStatusFailed
+	UploadStatusDeleted
+)
+
+const (
+	uploadIDSize = 16
+)
+
+var (
+	ErrNotFound = errors.New("file is not found")
+
+	ErrFileExists     = errors.New("file already exists")
+	ErrUploadNotFound = errors.New("upload is not found")
+	ErrUploadActive   = errors.New("upload is in progress")
+
+	ErrInvalidRange    = errors.New("invalid byte range")
+	ErrNoFragmentSizes = errors.New("fragment sizes are not recorded")
+
+	statusNames = map[UploadStatus]string{
+		UploadStatusIncomplete: "incomplete",
+		UploadStatusComplete:   "complete",
+		UploadStatusFailed:     "failed",
+		UploadStatusDeleted:    "deleted",
+	}
 )
 
+func StatusName(status UploadStatus) string {
+	name, ok := statusNames[status]
+	if !ok {
+		return "unknown"
+	}
+
+	return name
+}
+
 func NewRegistry() (*Registry, error) {
-	f, err := os.ReadFile(fragmentsFile)
+	r := &Registry{
+		Namespaces: make(map[string]*Namespace),
+		Uploads:    make(map[string]*FileMeta),
+		Chunks:     make(map[string]*Chunk),
+		readers:    make(map[string]int),
+		sessions:   make(map[string]bool),
+	}
 
+	f, err := os.ReadFile(fragmentsFile)
 	if os.IsNotExist(err) {
-		return &Registry{
-			Files: make(map[string]*FileMeta),
-		}, nil
+		r.Namespaces[DefaultNamespace] = newNamespace(DefaultNamespace, NamespaceSettings{Replication: 1})
+		return r, nil
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
+	err = json.Unmarshal(f, r)
 	if err != nil {
 		return nil, err
 	}
 
-	var r Registry
-	err = json.Unmarshal(f, &r)
-	return &r, err
+	if r.Namespaces == nil {
+		r.Namespaces = make(map[string]*Namespace)
+	}
+
+	if r.Uploads == nil {
+		r.Uploads = make(map[string]*FileMeta)
+	}
+
+	if r.Chunks == nil {
+		r.Chunks = make(map[string]*Chunk)
+	}
+
+	migrated := false
+	for _, fm := range r.LegacyFiles {
+		migrated = fm.migrateFragments() || migrated
+	}
+	for _, ns := range r.Namespaces {
+		for _, fm := range ns.Files {
+			migrated = fm.migrateFragments() || migrated
+		}
+	}
+	for _, fm := range r.Uploads {
+		migrated = fm.migrateFragments() || migrated
+	}
+	for _, fm := range r.Garbage {
+		migrated = fm.migrateFragments() || migrated
+	}
+
+	// registries, created before staging was introduced, keep failed uploads among files
+	for filename, fm := range r.LegacyFiles {
+		if fm.Status != UploadStatusComplete {
+			fm.Status = UploadStatusFailed
+			r.Uploads[fm.StorageKey()] = fm
+			delete(r.LegacyFiles, filename)
+		}
+	}
+
+	migrated = r.migrateNamespaces() || migrated
+
+	if migrated {
+		err = r.store()
+		if err != nil {
+			return nil, fmt.Errorf("failed to store migrated fragment table: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+// migrateFragments converts fragment addresses, sizes and checksums to the fragment table. Sizes and offsets
+// of files, uploaded before sizes were recorded, are not known, unless the file has a single fragment.
+func (fm *FileMeta) migrateFragments() bool {
+	if len(fm.LegacyAddresses) == 0 {
+		return false
+	}
+
+	if len(fm.LegacyAddresses) == 1 && len(fm.LegacySizes) == 0 && fm.Size > 0 {
+		fm.LegacySizes = []int64{fm.Size}
+	}
+
+	var offset int64
+	for i, address := range fm.LegacyAddresses {
+		f := Fragment{
+			Index:    i,
+			Offset:   -1,
+			Length:   -1,
+			Replicas: []string{address},
+		}
+
+		if i < len(fm.LegacySizes) && offset >= 0 {
+			f.Offset = offset
+			f.Length = fm.LegacySizes[i]
+			offset += f.Length
+		} else {
+			offset = -1
+		}
+
+		if i < len(fm.LegacyChecksums) {
+			f.Checksum = fm.LegacyChecksums[i]
+		}
+
+		fm.Fragments = append(fm.Fragments, f)
+	}
+
+	// files uploaded before size was recorded
+	if fm.Size == 0 && offset > 0 {
+		fm.Size = offset
+	}
+
+	fm.LegacyAddresses, fm.LegacySizes, fm.LegacyChecksums = nil, nil, nil
+
+	return true
+}
+
+// migrateNamespaces moves files of registries, created before namespaces were introduced, to the default namespace.
+func (r *Registry) migrateNamespaces() bool {
+	migrated := false
+	if _, ok := r.Namespaces[DefaultNamespace]; !ok {
+		r.Namespaces[DefaultNamespace] = newNamespace(DefaultNamespace, NamespaceSettings{Replication: 1})
+		migrated = true
+	}
+
+	now := time.Now()
+	for filename, fm := range r.LegacyFiles {
+		// files uploaded before creation time was recorded are kept by retention from the migration
+		if fm.CreatedAt.IsZero() {
+			fm.CreatedAt, fm.CompletedAt = now, now
+		}
+
+		r.Namespaces[DefaultNamespace].Files[filename] = fm
+		migrated = true
+	}
+	r.LegacyFiles = nil
+
+	setNamespace := func(fm *FileMeta) {
+		if len(fm.Namespace) == 0 {
+			fm.Namespace = DefaultNamespace
+			migrated = true
+		}
+	}
+
+	for _, ns := range r.Namespaces {
+		for _, fm := range ns.Files {
+			setNamespace(fm)
+		}
+	}
+	for _, fm := range r.Uploads {
+		setNamespace(fm)
+	}
+	for _, fm := range r.Garbage {
+		setNamespace(fm)
+	}
+
+	return migrated
+}
+
+// StorageKey returns the name fragments of the file are stored with on bucket servers.
+// Files uploaded before staging was introduced are stored under their own name.
+func (fm *FileMeta) StorageKey() string {
+	if len(fm.UploadID) == 0 {
+		return fm.Name
+	}
+
+	return fm.UploadID
+}
+
+// parts returns whole fragments or chunks of the file in order, zero length means that size is not recorded.
+func (fm *FileMeta) parts() []FragmentRange {
+	if len(fm.Manifest) > 0 {
+		parts := make([]FragmentRange, 0, len(fm.Manifest))
+		for _, ref := range fm.Manifest {
+			parts = append(parts, FragmentRange{
+				Key:     ref.StorageKey(),
+				Codec:   ref.Codec,
+				DataKey: ref.DataKey,
+				Address: ref.Address,
+				Length:  ref.Size,
+			})
+		}
+
+		return parts
+	}
+
+	parts := make([]FragmentRange, 0, len(fm.Fragments))
+	for _, f := range fm.Fragments {
+		part := FragmentRange{
+			Key:      fm.StorageKey(),
+			Codec:    fm.Codec,
+			DataKey:  fm.DataKey,
+			Fragment: f.Index,
+			Address:  f.Replicas[0],
+			Replicas: f.Replicas,
+			Length:   max(f.Length, 0),
+		}
+
+		parts = append(parts, part)
+	}
+
+	return parts
+}
+
+// AllRanges returns ranges of the whole file, zero length means up to the end of the fragment.
+func (fm *FileMeta) AllRanges() []FragmentRange {
+	ranges := fm.parts()
+	for i := range ranges {
+		ranges[i].Length = 0
+	}
+
+	return ranges
+}
+
+// Ranges maps length bytes of the file starting from offset to the fragments, containing them.
+func (fm *FileMeta) Ranges(offset, length int64) ([]FragmentRange, error) {
+	for _, f := range fm.Fragments {
+		if f.Length < 0 {
+			return nil, ErrNoFragmentSizes
+		}
+	}
+
+	if offset < 0 || length < 0 || offset+length > fm.Size {
+		return nil, ErrInvalidRange
+	}
+
+	var (
+		ranges         []FragmentRange
+		fragmentOffset int64
+	)
+	for _, part := range fm.parts() {
+		if length == 0 {
+			break
+		}
+
+		size := part.Length
+		if offset >= fragmentOffset+size {
+			fragmentOffset += size
+			continue
+		}
+
+		start := offset - fragmentOffset
+		n := min(size-start, length)
+
+		part.Offset = start
+		part.Length = n
+		ranges = append(ranges, part)
+
+		offset += n
+		length -= n
+		fragmentOffset += size
+	}
+
+	return ranges, nil
+}
+
+// Committed returns number of bytes in stored fragments.
+func (fm *FileMeta) Committed() int64 {
+	var committed int64
+	for _, f := range fm.Fragments {
+		committed += max(f.Length, 0)
+	}
+
+	for _, ref := range fm.Manifest {
+		committed += ref.Size
+	}
+
+	return committed
+}
+
+func (fm *FileMeta) clone() *FileMeta {
+	c := *fm
+	c.Fragments = make([]Fragment, 0, len(fm.Fragments))
+	for _, f := range fm.Fragments {
+		f.Replicas = append([]string(nil), f.Replicas...)
+		c.Fragments = append(c.Fragments, f)
+	}
+	c.HashState = append([]byte(nil), fm.HashState...)
+	c.Metadata = maps.Clone(fm.Metadata)
+	c.Manifest = append([]ChunkRef(nil), fm.Manifest...)
+
+	return &c
 }
 
 func (r *Registry) store() error {
@@ -57,42 +432,452 @@ func (r *Registry) store() error {
 	return os.WriteFile(fragmentsFile, f, 0644)
 }
 
-func (r *Registry) AddFragment(filename, address string) error {
+// BeginUpload stages a new upload of the file and returns its session.
+// The file name is switched to the staged fragments only when the upload is complete.
+// The namespace must exist and the file must fit its quota.
+func (r *Registry) BeginUpload(namespace, filename string, size int64, opts UploadOptions) (*FileMeta, error) {
+	b := make([]byte, uploadIDSize)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, err
+	}
+	uploadID := hex.EncodeToString(b)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ns, err := r.namespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := ns.Files[filename]; ok && opts.Exclusive {
+		return nil, ErrFileExists
+	}
+
+	if !ns.fits(filename, size) {
+		return nil, ErrQuotaExceeded
+	}
+
+	now := time.Now().UTC()
+	fm := &FileMeta{
+		Status:      UploadStatusIncomplete,
+		Namespace:   namespace,
+		Name:        filename,
+		UploadID:    uploadID,
+		Size:        size,
+		Checksum:    opts.Checksum,
+		Exclusive:   opts.Exclusive,
+		Codec:       opts.Codec,
+		DataKey:     opts.DataKey,
+		ContentType: opts.ContentType,
+		Metadata:    opts.Metadata,
+		Replication: ns.Replication,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	r.Uploads[uploadID] = fm
+	r.sessions[uploadID] = true
+
+	return fm.clone(), r.store()
+}
+
+// ResumeUpload continues interrupted upload of the same file, only one session of the upload is allowed at a time.
+func (r *Registry) ResumeUpload(uploadID, namespace, filename string, size int64) (*FileMeta, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	fm, ok := r.Uploads[uploadID]
+	if !ok || fm.Status != UploadStatusIncomplete || fm.Namespace != namespace || fm.Name != filename || fm.Size != size {
+		return nil, ErrUploadNotFound
+	}
+
+	if r.sessions[uploadID] {
+		return nil, ErrUploadActive
+	}
+
+	r.sessions[uploadID] = true
+	fm.UpdatedAt = time.Now().UTC()
+
+	return fm.clone(), r.store()
+}
+
+// Detach ends upload session, keeping the upload incomplete, so it could be resumed later.
+func (r *Registry) Detach(uploadID string) error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	fm, ok := r.Files[filename]
+	delete(r.sessions, uploadID)
+
+	fm, ok := r.Uploads[uploadID]
 	if !ok {
-		r.Files[filename] = &FileMeta{
-			Name:      filename,
-			Addresses: []string{address},
+		return fmt.Errorf("no upload with %s ID", uploadID)
+	}
+
+	fm.UpdatedAt = time.Now().UTC()
+
+	return r.store()
+}
+
+// Expire fails incomplete uploads, which have no session for longer than ttl.
+func (r *Registry) Expire(ttl time.Duration) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	deadline := time.Now().Add(-ttl)
+
+	expired := 0
+	for uploadID, fm := range r.Uploads {
+		if fm.Status != UploadStatusIncomplete || r.sessions[uploadID] || fm.UpdatedAt.After(deadline) {
+			continue
 		}
-	} else {
-		fm.Addresses = append(fm.Addresses, address)
+
+		fm.Status = UploadStatusFailed
+		expired++
+	}
+
+	if expired == 0 {
+		return nil
+	}
+
+	return r.store()
+}
+
+// AddFragment records stored fragment of the upload with addresses of its replicas. The hash state allows
+// to continue file checksum calculation, when upload is resumed.
+func (r *Registry) AddFragment(uploadID string, replicas []string, size int64, checksum string, hashState []byte) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	fm, ok := r.Uploads[uploadID]
+	if !ok {
+		return fmt.Errorf("no upload with %s ID", uploadID)
+	}
+
+	fm.Fragments = append(fm.Fragments, Fragment{
+		Index:    len(fm.Fragments),
+		Offset:   fm.Committed(),
+		Length:   size,
+		Checksum: checksum,
+		Replicas: append([]string(nil), replicas...),
+	})
+	fm.HashState = hashState
+	fm.UpdatedAt = time.Now().UTC()
+
+	return r.store()
+}
+
+func (r *Registry) SetChecksum(uploadID, checksum string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	fm, ok := r.Uploads[uploadID]
+	if !ok {
+		return fmt.Errorf("no upload with %s ID", uploadID)
 	}
 
+	fm.Checksum = checksum
+
 	return r.store()
 }
 
-func (r *Registry) SetStatus(filename string, status UploadStatus) error {
+// SetStatus updates status of the staged upload, complete upload replaces the file with the same name.
+func (r *Registry) SetStatus(uploadID string, status UploadStatus) error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	fm, ok := r.Files[filename]
+	fm, ok := r.Uploads[uploadID]
 	if !ok {
-		return fmt.Errorf("no fragments of %s file", filename)
+		return fmt.Errorf("no upload with %s ID", uploadID)
 	}
 
 	fm.Status = status
+	fm.UpdatedAt = time.Now().UTC()
+	delete(r.sessions, uploadID)
+
+	if status != UploadStatusComplete {
+		return r.store()
+	}
+
+	// namespace could be deleted or its quota used by other uploads in the meantime
+	ns, err := r.namespace(fm.Namespace)
+	if err == nil && !ns.fits(fm.Name, fm.Size) {
+		err = ErrQuotaExceeded
+	}
+	if _, ok := r.files(fm.Namespace)[fm.Name]; ok && fm.Exclusive {
+		err = ErrFileExists
+	}
+	if err != nil {
+		fm.Status = UploadStatusFailed
+		r.store()
+		return err
+	}
+
+	fm.HashState = nil
+	fm.CompletedAt = fm.UpdatedAt
+	delete(r.Uploads, uploadID)
+
+	if old, ok := ns.Files[fm.Name]; ok {
+		r.Garbage = append(r.Garbage, old)
+	}
+	ns.Files[fm.Name] = fm
+
+	return r.store()
+}
+
+// Stat returns committed file meta.
+func (r *Registry) Stat(namespace, filename string) (*FileMeta, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	fm, ok := r.files(namespace)[filename]
+	if !ok {
+		return nil, false
+	}
+
+	return fm.clone(), true
+}
+
+// List returns up to limit committed files of the namespace, which names have the prefix and follow the cursor
+// in lexicographical order. Returned cursor is empty, when there are no more files.
+func (r *Registry) List(namespace, prefix, cursor string, limit int, match func(*FileMeta) bool) ([]*FileMeta, string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	files := r.files(namespace)
+
+	names := make([]string, 0, len(files))
+	for name, fm := range files {
+		if strings.HasPrefix(name, prefix) && name > cursor && (match == nil || match(fm)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	next := ""
+	if len(names) > limit {
+		names = names[:limit]
+		next = names[limit-1]
+	}
+
+	list := make([]*FileMeta, 0, len(names))
+	for _, name := range names {
+		list = append(list, files[name].clone())
+	}
+
+	return list, next
+}
+
+// Acquire returns committed file meta and prevents its fragments from deletion until Release is called.
+func (r *Registry) Acquire(namespace, filename string) (*FileMeta, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	fm, ok := r.files(namespace)[filename]
+	if !ok {
+		return nil, false
+	}
+
+	r.readers[fm.StorageKey()]++
+
+	return fm.clone(), true
+}
+
+func (r *Registry) Release(fm *FileMeta) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key := fm.StorageKey()
+
+	r.readers[key]--
+	if r.readers[key] <= 0 {
+		delete(r.readers, key)
+	}
+}
+
+// Tombstone marks the file as deleted: it is not available anymore and its fragments are queued for deletion.
+func (r *Registry) Tombstone(namespace, filename string) (*FileMeta, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	files := r.files(namespace)
+
+	fm, ok := files[filename]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	delete(files, filename)
+	fm.Status = UploadStatusDeleted
+	r.Garbage = append(r.Garbage, fm)
+
+	return fm.clone(), r.store()
+}
+
+// InUse reports whether fragments with the storage key are being downloaded.
+func (r *Registry) InUse(key string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.readers[key] > 0
+}
+
+// Obsolete returns failed uploads, replaced and deleted files, which fragments can be deleted.
+func (r *Registry) Obsolete() []*FileMeta {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var obsolete []*FileMeta
+	for _, fm := range r.Uploads {
+		if fm.Status == UploadStatusFailed {
+			obsolete = append(obsolete, fm.clone())
+		}
+	}
+
+	for _, fm := range r.Garbage {
+		if r.readers[fm.StorageKey()] == 0 {
+			obsolete = append(obsolete, fm.clone())
+		}
+	}
+
+	return obsolete
+}
+
+// Unsized returns committed files with fragments of unknown length, they were uploaded before fragment sizes
+// were recorded.
+func (r *Registry) Unsized() []*FileMeta {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var unsized []*FileMeta
+	for _, ns := range r.Namespaces {
+		for _, fm := range ns.Files {
+			if slices.ContainsFunc(fm.Fragments, func(f Fragment) bool { return f.Length < 0 }) {
+				unsized = append(unsized, fm.clone())
+			}
+		}
+	}
+
+	return unsized
+}
+
+// SetFragmentLengths records lengths of all fragments of the committed file with the storage key, offsets of
+// fragments and the file size are computed from them.
+func (r *Registry) SetFragmentLengths(namespace, filename, key string, lengths []int64) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// file could be replaced, while its fragments are measured
+	fm, ok := r.files(namespace)[filename]
+	if !ok || fm.StorageKey() != key {
+		return ErrNotFound
+	}
+
+	if len(lengths) != len(fm.Fragments) {
+		return fmt.Errorf("file has %d fragments, %d lengths are given", len(fm.Fragments), len(lengths))
+	}
+
+	var offset int64
+	for i := range fm.Fragments {
+		fm.Fragments[i].Offset = offset
+		fm.Fragments[i].Length = lengths[i]
+		offset += lengths[i]
+	}
+	fm.Size = offset
 
 	return r.store()
 }
 
-func (r *Registry) Delete(filename string) error {
+// Purge forgets obsolete upload or replaced file with the storage key, chunks of deduplicated file are released.
+func (r *Registry) Purge(key string) error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	delete (r.Files, filename)
+	if fm, ok := r.Uploads[key]; ok {
+		r.unref(fm)
+		delete(r.Uploads, key)
+	}
+
+	garbage := r.Garbage[:0]
+	for _, fm := range r.Garbage {
+		if fm.StorageKey() != key {
+			garbage = append(garbage, fm)
+			continue
+		}
+
+		r.unref(fm)
+	}
+	r.Garbage = garbage
 
 	return r.store()
 }
+
+// RewrapKeys replaces data keys of all files and chunks with keys returned by rewrap, which reports
+// whether the key was changed. It returns number of changed keys.
+func (r *Registry) RewrapKeys(rewrap func(*encryption.WrappedKey) (*encryption.WrappedKey, bool, error)) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	rewrapped := 0
+	update := func(key **encryption.WrappedKey) error {
+		if *key == nil {
+			return nil
+		}
+
+		w, changed, err := rewrap(*key)
+		if err != nil {
+			return err
+		}
+
+		if changed {
+			*key = w
+			rewrapped++
+		}
+
+		return nil
+	}
+
+	updateFile := func(fm *FileMeta) error {
+		err := update(&fm.DataKey)
+		if err != nil {
+			return fmt.Errorf("failed to rewrap key of %s: %w", fm.Name, err)
+		}
+
+		for i := range fm.Manifest {
+			err = update(&fm.Manifest[i].DataKey)
+			if err != nil {
+				return fmt.Errorf("failed to rewrap key of %s chunk: %w", fm.Manifest[i].Hash, err)
+			}
+		}
+
+		return nil
+	}
+
+	var err error
+	for _, ns := range r.Namespaces {
+		for _, fm := range ns.Files {
+			err = errors.Join(err, updateFile(fm))
+		}
+	}
+
+	for _, fm := range r.Uploads {
+		err = errors.Join(err, updateFile(fm))
+	}
+
+	for _, fm := range r.Garbage {
+		err = errors.Join(err, updateFile(fm))
+	}
+
+	for hash, c := range r.Chunks {
+		chunkErr := update(&c.DataKey)
+		if chunkErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to rewrap key of %s chunk: %w", hash, chunkErr))
+		}
+	}
+
+	if rewrapped == 0 {
+		return 0, err
+	}
+
+	return rewrapped, errors.Join(err, r.store())
+}
@@ -0,0 +1,243 @@
+package fragment
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// useRegistryDir runs the test in a temporary directory, where the registry file is kept, with the content.
+func useRegistryDir(t *testing.T, content string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	err = os.WriteFile(fragmentsFile, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewRegistryMigration(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		wantCommitted bool
+		wantSize      int64
+		wantFragments []Fragment
+		wantUnsized   bool
+	}{
+		{
+			name: "file without fragment sizes",
+			content: fmt.Sprintf(`{"files": {"a.bin": {"status": %d, "filename": "a.bin",
+				"addresses": ["b1:6000", "b2:6000"]}}}`, UploadStatusComplete),
+			wantCommitted: true,
+			wantSize:      0,
+			wantFragments: []Fragment{
+				{Index: 0, Offset: -1, Length: -1, Replicas: []string{"b1:6000"}},
+				{Index: 1, Offset: -1, Length: -1, Replicas: []string{"b2:6000"}},
+			},
+			wantUnsized: true,
+		},
+		{
+			name: "file with fragment sizes and checksums",
+			content: fmt.Sprintf(`{"files": {"a.bin": {"status": %d, "filename": "a.bin",
+				"addresses": ["b1:6000", "b2:6000"], "sizes": [10, 5], "checksums": ["c1", "c2"]}}}`, UploadStatusComplete),
+			wantCommitted: true,
+			wantSize:      15,
+			wantFragments: []Fragment{
+				{Index: 0, Offset: 0, Length: 10, Checksum: "c1", Replicas: []string{"b1:6000"}},
+				{Index: 1, Offset: 10, Length: 5, Checksum: "c2", Replicas: []string{"b2:6000"}},
+			},
+		},
+		{
+			name: "single fragment of known file size",
+			content: fmt.Sprintf(`{"files": {"a.bin": {"status": %d, "filename": "a.bin", "size": 7,
+				"addresses": ["b1:6000"]}}}`, UploadStatusComplete),
+			wantCommitted: true,
+			wantSize:      7,
+			wantFragments: []Fragment{
+				{Index: 0, Offset: 0, Length: 7, Replicas: []string{"b1:6000"}},
+			},
+		},
+		{
+			name: "incomplete file is moved to failed uploads",
+			content: fmt.Sprintf(`{"files": {"a.bin": {"status": %d, "filename": "a.bin",
+				"addresses": ["b1:6000"], "sizes": [3]}}}`, UploadStatusIncomplete),
+			wantCommitted: false,
+			wantSize:      3,
+			wantFragments: []Fragment{
+				{Index: 0, Offset: 0, Length: 3, Replicas: []string{"b1:6000"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRegistryDir(t, tt.content)
+
+			r, err := NewRegistry()
+			if err != nil {
+				t.Fatalf("NewRegistry() error = %v", err)
+			}
+
+			fm, committed := r.Stat(DefaultNamespace, "a.bin")
+			if committed != tt.wantCommitted {
+				t.Fatalf("file is committed = %v, want %v", committed, tt.wantCommitted)
+			}
+
+			if !committed {
+				upload, ok := r.Uploads["a.bin"]
+				if !ok {
+					t.Fatal("file is not found among uploads")
+				}
+
+				if upload.Status != UploadStatusFailed {
+					t.Errorf("upload status = %s, want %s", StatusName(upload.Status), StatusName(UploadStatusFailed))
+				}
+				fm = upload
+			}
+
+			if fm.Namespace != DefaultNamespace {
+				t.Errorf("namespace = %q, want %q", fm.Namespace, DefaultNamespace)
+			}
+
+			if fm.Size != tt.wantSize {
+				t.Errorf("size = %d, want %d", fm.Size, tt.wantSize)
+			}
+
+			if !reflect.DeepEqual(fm.Fragments, tt.wantFragments) {
+				t.Errorf("fragments = %+v, want %+v", fm.Fragments, tt.wantFragments)
+			}
+
+			if committed && (fm.CreatedAt.IsZero() || fm.CompletedAt.IsZero()) {
+				t.Error("creation and completion time of migrated file are not set")
+			}
+
+			if unsized := len(r.Unsized()) > 0; unsized != tt.wantUnsized {
+				t.Errorf("file is unsized = %v, want %v", unsized, tt.wantUnsized)
+			}
+
+			// migrated registry is stored, so it is loaded the same way again
+			reloaded, err := NewRegistry()
+			if err != nil {
+				t.Fatalf("NewRegistry() error = %v", err)
+			}
+
+			if len(reloaded.LegacyFiles) > 0 {
+				t.Errorf("%d legacy files are left in stored registry", len(reloaded.LegacyFiles))
+			}
+
+			if got, _ := reloaded.Stat(DefaultNamespace, "a.bin"); committed && !reflect.DeepEqual(got.Fragments, tt.wantFragments) {
+				t.Errorf("stored fragments = %+v, want %+v", got.Fragments, tt.wantFragments)
+			}
+		})
+	}
+}
+
+func TestFileMetaRanges(t *testing.T) {
+	fm := &FileMeta{
+		Name:     "a.bin",
+		UploadID: "upload",
+		Size:     25,
+		Codec:    "gzip",
+		Fragments: []Fragment{
+			{Index: 0, Offset: 0, Length: 10, Replicas: []string{"b1:6000"}},
+			{Index: 1, Offset: 10, Length: 10, Replicas: []string{"b2:6000", "b3:6000"}},
+			{Index: 2, Offset: 20, Length: 5, Replicas: []string{"b3:6000"}},
+		},
+	}
+
+	part := func(fragment int, offset, length int64) FragmentRange {
+		return FragmentRange{
+			Key:      "upload",
+			Codec:    "gzip",
+			Fragment: fragment,
+			Address:  fm.Fragments[fragment].Replicas[0],
+			Replicas: fm.Fragments[fragment].Replicas,
+			Offset:   offset,
+			Length:   length,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		offset  int64
+		length  int64
+		want    []FragmentRange
+		wantErr error
+	}{
+		{
+			name:   "whole file",
+			offset: 0,
+			length: 25,
+			want:   []FragmentRange{part(0, 0, 10), part(1, 0, 10), part(2, 0, 5)},
+		},
+		{
+			name:   "inside fragment",
+			offset: 12,
+			length: 3,
+			want:   []FragmentRange{part(1, 2, 3)},
+		},
+		{
+			name:   "across fragments",
+			offset: 5,
+			length: 17,
+			want:   []FragmentRange{part(0, 5, 5), part(1, 0, 10), part(2, 0, 2)},
+		},
+		{
+			name:   "fragment boundary",
+			offset: 20,
+			length: 5,
+			want:   []FragmentRange{part(2, 0, 5)},
+		},
+		{name: "empty range", offset: 10, length: 0, want: nil},
+		{name: "past the end", offset: 20, length: 6, wantErr: ErrInvalidRange},
+		{name: "negative offset", offset: -1, length: 5, wantErr: ErrInvalidRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fm.Ranges(tt.offset, tt.length)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Ranges() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ranges() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if committed := fm.Committed(); committed != 25 {
+		t.Errorf("Committed() = %d, want 25", committed)
+	}
+
+	// files, migrated without fragment sizes, are only read as a whole
+	unsized := &FileMeta{
+		Name: "a.bin",
+		Fragments: []Fragment{
+			{Index: 0, Offset: -1, Length: -1, Replicas: []string{"b1:6000"}},
+		},
+	}
+
+	_, err := unsized.Ranges(0, 0)
+	if !errors.Is(err, ErrNoFragmentSizes) {
+		t.Errorf("Ranges() of unsized file error = %v, want %v", err, ErrNoFragmentSizes)
+	}
+
+	if all := unsized.AllRanges(); len(all) != 1 || all[0].Length != 0 {
+		t.Errorf("AllRanges() of unsized file = %+v, want the whole fragment", all)
+	}
+}
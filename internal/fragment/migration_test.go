@@ -0,0 +1,98 @@
+package fragment
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMigratedFileSize(t *testing.T) {
+	useRegistryDir(t, fmt.Sprintf(`{"files": {"a.bin": {"status": %d, "filename": "a.bin",
+		"addresses": ["b1:6000", "b2:6000", "b3:6000"]}}}`, UploadStatusComplete))
+
+	r, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		key       string
+		lengths   []int64
+		wantErr   bool
+	}{
+		{name: "another namespace", namespace: "other", key: "a.bin", lengths: []int64{1, 2, 3}, wantErr: true},
+		{name: "replaced file", namespace: DefaultNamespace, key: "upload", lengths: []int64{1, 2, 3}, wantErr: true},
+		{name: "lengths of some fragments", namespace: DefaultNamespace, key: "a.bin", lengths: []int64{1, 2}, wantErr: true},
+		{name: "lengths of all fragments", namespace: DefaultNamespace, key: "a.bin", lengths: []int64{4, 4, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.SetFragmentLengths(tt.namespace, "a.bin", tt.key, tt.lengths)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetFragmentLengths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	fm, _ := r.Stat(DefaultNamespace, "a.bin")
+	if fm.Size != 10 {
+		t.Errorf("size = %d, want 10", fm.Size)
+	}
+
+	for i, want := range []int64{0, 4, 8} {
+		if fm.Fragments[i].Offset != want {
+			t.Errorf("%d fragment offset = %d, want %d", i, fm.Fragments[i].Offset, want)
+		}
+	}
+
+	if unsized := r.Unsized(); len(unsized) > 0 {
+		t.Errorf("%d files are unsized after their lengths are set", len(unsized))
+	}
+}
+
+func TestExpireMigratedFiles(t *testing.T) {
+	day := 24 * time.Hour
+	old := time.Now().Add(-2 * day).UTC().Format(time.RFC3339)
+
+	useRegistryDir(t, fmt.Sprintf(`{
+		"namespaces": {"default": {"name": "default", "replication": 1, "retention": %d, "files": {
+			"unknown.bin": {"status": %d, "namespace": "default", "filename": "unknown.bin", "fragments": []},
+			"old.bin": {"status": %[2]d, "namespace": "default", "filename": "old.bin", "created_at": %[3]q, "fragments": []}
+		}}},
+		"files": {"legacy.bin": {"status": %[2]d, "filename": "legacy.bin", "addresses": ["b1:6000"]}}
+	}`, day, UploadStatusComplete, old))
+
+	r, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	expired, err := r.ExpireFiles()
+	if err != nil {
+		t.Fatalf("ExpireFiles() error = %v", err)
+	}
+
+	if expired != 1 {
+		t.Errorf("%d files are expired, want 1", expired)
+	}
+
+	tests := []struct {
+		filename string
+		wantKept bool
+	}{
+		{filename: "unknown.bin", wantKept: true},
+		{filename: "legacy.bin", wantKept: true},
+		{filename: "old.bin", wantKept: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			if _, kept := r.Stat(DefaultNamespace, tt.filename); kept != tt.wantKept {
+				t.Errorf("file is kept = %v, want %v", kept, tt.wantKept)
+			}
+		})
+	}
+}
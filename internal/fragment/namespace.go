@@ -0,0 +1,263 @@
+package fragment
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+type (
+	// Namespace is a separate keyspace of files with own storage settings.
+	Namespace struct {
+		Name      string    `json:"name"`
+		CreatedAt time.Time `json:"created_at"`
+		NamespaceSettings
+
+		Files map[string]*FileMeta `json:"files"` // committed files by name
+	}
+
+	NamespaceSettings struct {
+		Replication int           `json:"replication"`         // bucket servers storing every fragment
+		Quota       int64         `json:"quota,omitempty"`     // total size of committed files, unlimited when 0
+		Retention   time.Duration `json:"retention,omitempty"` // committed files are deleted after it, kept forever when 0
+	}
+
+	// NamespaceUsage is a namespace with number and total size of its files.
+	NamespaceUsage struct {
+		Namespace
+		FileCount int
+		Size      int64
+	}
+)
+
+const (
+	// DefaultNamespace keeps files uploaded without a namespace, it can't be deleted.
+	DefaultNamespace = "default"
+
+	maxNamespaceName = 64
+)
+
+var (
+	ErrNamespaceNotFound = errors.New("namespace is not found")
+	ErrNamespaceNotEmpty = errors.New("namespace has files or active uploads")
+	ErrDefaultNamespace  = errors.New("default namespace can't be deleted")
+	ErrInvalidNamespace  = errors.New("invalid namespace")
+
+	ErrQuotaExceeded = errors.New("namespace quota exceeded")
+)
+
+func newNamespace(name string, settings NamespaceSettings) *Namespace {
+	return &Namespace{
+		Name:              name,
+		CreatedAt:         time.Now().UTC(),
+		NamespaceSettings: settings,
+		Files:             make(map[string]*FileMeta),
+	}
+}
+
+// validNamespaceName reports whether the name could be used as a path segment of API endpoints.
+func validNamespaceName(name string) bool {
+	if len(name) == 0 || len(name) > maxNamespaceName {
+		return false
+	}
+
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' && c != '.' {
+			return false
+		}
+	}
+
+	return name != "." && name != ".."
+}
+
+func (s NamespaceSettings) validate() error {
+	if s.Replication < 1 || s.Quota < 0 || s.Retention < 0 {
+		return ErrInvalidNamespace
+	}
+
+	return nil
+}
+
+// size returns total size of committed files.
+func (ns *Namespace) size() int64 {
+	var size int64
+	for _, fm := range ns.Files {
+		size += fm.Size
+	}
+
+	return size
+}
+
+// fits reports whether the file of the size could replace the file with the same name without exceeding the quota.
+func (ns *Namespace) fits(filename string, size int64) bool {
+	if ns.Quota == 0 {
+		return true
+	}
+
+	used := ns.size()
+	if old, ok := ns.Files[filename]; ok {
+		used -= old.Size
+	}
+
+	return used+size <= ns.Quota
+}
+
+func (ns *Namespace) usage() NamespaceUsage {
+	u := NamespaceUsage{
+		Namespace: *ns,
+		FileCount: len(ns.Files),
+		Size:      ns.size(),
+	}
+	u.Files = nil
+
+	return u
+}
+
+// namespace returns the namespace of committed files, registry lock must be held.
+func (r *Registry) namespace(name string) (*Namespace, error) {
+	ns, ok := r.Namespaces[name]
+	if !ok {
+		return nil, ErrNamespaceNotFound
+	}
+
+	return ns, nil
+}
+
+// files returns committed files of the namespace, there are none in unknown namespace.
+func (r *Registry) files(namespace string) map[string]*FileMeta {
+	ns, ok := r.Namespaces[namespace]
+	if !ok {
+		return nil
+	}
+
+	return ns.Files
+}
+
+// ListNamespaces returns all namespaces with their usage in lexicographical order.
+func (r *Registry) ListNamespaces() []NamespaceUsage {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	namespaces := make([]NamespaceUsage, 0, len(r.Namespaces))
+	for _, ns := range r.Namespaces {
+		namespaces = append(namespaces, ns.usage())
+	}
+
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+
+	return namespaces
+}
+
+// Namespace returns settings and usage of the namespace.
+func (r *Registry) Namespace(name string) (NamespaceUsage, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ns, err := r.namespace(name)
+	if err != nil {
+		return NamespaceUsage{}, err
+	}
+
+	return ns.usage(), nil
+}
+
+// PutNamespace creates the namespace or updates its settings, it reports whether the namespace was created.
+// New quota and retention apply to existing files as well, new replication applies only to new uploads.
+func (r *Registry) PutNamespace(name string, settings NamespaceSettings) (bool, error) {
+	if !validNamespaceName(name) {
+		return false, ErrInvalidNamespace
+	}
+
+	err := settings.validate()
+	if err != nil {
+		return false, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ns, ok := r.Namespaces[name]
+	if ok {
+		ns.NamespaceSettings = settings
+		return false, r.store()
+	}
+
+	r.Namespaces[name] = newNamespace(name, settings)
+
+	return true, r.store()
+}
+
+// DeleteNamespace removes empty namespace.
+func (r *Registry) DeleteNamespace(name string) error {
+	if name == DefaultNamespace {
+		return ErrDefaultNamespace
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ns, err := r.namespace(name)
+	if err != nil {
+		return err
+	}
+
+	if len(ns.Files) > 0 {
+		return ErrNamespaceNotEmpty
+	}
+
+	for _, fm := range r.Uploads {
+		if fm.Namespace == name && fm.Status == UploadStatusIncomplete {
+			return ErrNamespaceNotEmpty
+		}
+	}
+
+	delete(r.Namespaces, name)
+
+	return r.store()
+}
+
+// ExpireFiles deletes committed files, which are kept longer than retention of their namespace,
+// fragments of deleted files are queued for deletion. It returns number of deleted files.
+func (r *Registry) ExpireFiles() (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+
+	expired := 0
+	for _, ns := range r.Namespaces {
+		if ns.Retention == 0 {
+			continue
+		}
+
+		for filename, fm := range ns.Files {
+			// files committed before completion time was recorded
+			completed := fm.CompletedAt
+			if completed.IsZero() {
+				completed = fm.CreatedAt
+			}
+
+			// age of the file is unknown, so it is never expired
+			if completed.IsZero() {
+				continue
+			}
+
+			if now.Sub(completed) < ns.Retention {
+				continue
+			}
+
+			delete(ns.Files, filename)
+			fm.Status = UploadStatusDeleted
+			r.Garbage = append(r.Garbage, fm)
+			expired++
+		}
+	}
+
+	if expired == 0 {
+		return 0, nil
+	}
+
+	return expired, r.store()
+}
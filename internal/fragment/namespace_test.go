@@ -0,0 +1,221 @@
+package fragment
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+
+	useRegistryDir(t, "{}")
+
+	r, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	return r
+}
+
+func commitFile(r *Registry, namespace, filename string, size int64) error {
+	fm, err := r.BeginUpload(namespace, filename, size, UploadOptions{})
+	if err != nil {
+		return err
+	}
+
+	err = r.AddFragment(fm.UploadID, []string{"b1:6000"}, size, "", nil)
+	if err != nil {
+		return err
+	}
+
+	return r.SetStatus(fm.UploadID, UploadStatusComplete)
+}
+
+func TestNamespaceQuota(t *testing.T) {
+	r := newTestRegistry(t)
+
+	_, err := r.PutNamespace("small", NamespaceSettings{Replication: 1, Quota: 100})
+	if err != nil {
+		t.Fatalf("PutNamespace() error = %v", err)
+	}
+
+	err = commitFile(r, "small", "a", 60)
+	if err != nil {
+		t.Fatalf("commit of a file within quota error = %v", err)
+	}
+
+	_, err = r.BeginUpload("small", "b", 41, UploadOptions{})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("BeginUpload() over quota error = %v, want %v", err, ErrQuotaExceeded)
+	}
+
+	// replaced file doesn't count
+	err = commitFile(r, "small", "a", 100)
+	if err != nil {
+		t.Errorf("commit of replacing file error = %v", err)
+	}
+
+	// both uploads fit, when they are started, but only the first one is committed
+	err = commitFile(r, "small", "a", 10)
+	if err != nil {
+		t.Fatalf("commit of replacing file error = %v", err)
+	}
+
+	first, err := r.BeginUpload("small", "b", 90, UploadOptions{})
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+	second, err := r.BeginUpload("small", "c", 90, UploadOptions{})
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+
+	err = r.SetStatus(first.UploadID, UploadStatusComplete)
+	if err != nil {
+		t.Fatalf("SetStatus() error = %v", err)
+	}
+
+	err = r.SetStatus(second.UploadID, UploadStatusComplete)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("SetStatus() over quota error = %v, want %v", err, ErrQuotaExceeded)
+	}
+	if _, ok := r.Stat("small", "c"); ok {
+		t.Error("file over quota is committed")
+	}
+
+	ns, err := r.Namespace("small")
+	if err != nil {
+		t.Fatalf("Namespace() error = %v", err)
+	}
+	if ns.FileCount != 2 || ns.Size != 100 {
+		t.Errorf("namespace has %d files of %d bytes, want 2 files of 100 bytes", ns.FileCount, ns.Size)
+	}
+
+	// other namespaces are not limited
+	err = commitFile(r, DefaultNamespace, "b", 1000)
+	if err != nil {
+		t.Errorf("commit to default namespace error = %v", err)
+	}
+}
+
+func TestNamespaceRetention(t *testing.T) {
+	r := newTestRegistry(t)
+
+	_, err := r.PutNamespace("tmp", NamespaceSettings{Replication: 1, Retention: time.Hour})
+	if err != nil {
+		t.Fatalf("PutNamespace() error = %v", err)
+	}
+
+	for _, filename := range []string{"old", "new"} {
+		for _, namespace := range []string{"tmp", DefaultNamespace} {
+			err = commitFile(r, namespace, filename, 10)
+			if err != nil {
+				t.Fatalf("commit of %s/%s error = %v", namespace, filename, err)
+			}
+		}
+	}
+
+	for _, namespace := range []string{"tmp", DefaultNamespace} {
+		r.Namespaces[namespace].Files["old"].CompletedAt = time.Now().Add(-2 * time.Hour)
+	}
+
+	expired, err := r.ExpireFiles()
+	if err != nil {
+		t.Fatalf("ExpireFiles() error = %v", err)
+	}
+	if expired != 1 {
+		t.Errorf("ExpireFiles() = %d, want 1", expired)
+	}
+
+	if _, ok := r.Stat("tmp", "old"); ok {
+		t.Error("expired file is kept")
+	}
+	for _, file := range [][2]string{{"tmp", "new"}, {DefaultNamespace, "old"}, {DefaultNamespace, "new"}} {
+		if _, ok := r.Stat(file[0], file[1]); !ok {
+			t.Errorf("%s/%s file is expired", file[0], file[1])
+		}
+	}
+
+	obsolete := r.Obsolete()
+	if len(obsolete) != 1 || obsolete[0].Namespace != "tmp" || obsolete[0].Name != "old" {
+		t.Errorf("Obsolete() = %v, want fragments of expired file", obsolete)
+	}
+
+	expired, err = r.ExpireFiles()
+	if err != nil || expired != 0 {
+		t.Errorf("ExpireFiles() = %d, %v, want nothing to expire", expired, err)
+	}
+}
+
+func TestPutNamespaceInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		settings  NamespaceSettings
+	}{
+		{name: "empty name", namespace: "", settings: NamespaceSettings{Replication: 1}},
+		{name: "upper case name", namespace: "Logs", settings: NamespaceSettings{Replication: 1}},
+		{name: "name with slash", namespace: "a/b", settings: NamespaceSettings{Replication: 1}},
+		{name: "dot dot name", namespace: "..", settings: NamespaceSettings{Replication: 1}},
+		{name: "no replication", namespace: "logs", settings: NamespaceSettings{}},
+		{name: "negative quota", namespace: "logs", settings: NamespaceSettings{Replication: 1, Quota: -1}},
+		{name: "negative retention", namespace: "logs", settings: NamespaceSettings{Replication: 1, Retention: -time.Hour}},
+	}
+
+	r := newTestRegistry(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.PutNamespace(tt.namespace, tt.settings)
+			if !errors.Is(err, ErrInvalidNamespace) {
+				t.Errorf("PutNamespace() error = %v, want %v", err, ErrInvalidNamespace)
+			}
+		})
+	}
+}
+
+func TestDeleteNamespace(t *testing.T) {
+	r := newTestRegistry(t)
+
+	err := r.DeleteNamespace(DefaultNamespace)
+	if !errors.Is(err, ErrDefaultNamespace) {
+		t.Errorf("DeleteNamespace(%q) error = %v, want %v", DefaultNamespace, err, ErrDefaultNamespace)
+	}
+
+	err = r.DeleteNamespace("unknown")
+	if !errors.Is(err, ErrNamespaceNotFound) {
+		t.Errorf("DeleteNamespace() of unknown namespace error = %v, want %v", err, ErrNamespaceNotFound)
+	}
+
+	_, err = r.PutNamespace("logs", NamespaceSettings{Replication: 1})
+	if err != nil {
+		t.Fatalf("PutNamespace() error = %v", err)
+	}
+
+	fm, err := r.BeginUpload("logs", "a", 10, UploadOptions{})
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+
+	err = r.DeleteNamespace("logs")
+	if !errors.Is(err, ErrNamespaceNotEmpty) {
+		t.Errorf("DeleteNamespace() with active upload error = %v, want %v", err, ErrNamespaceNotEmpty)
+	}
+
+	err = r.SetStatus(fm.UploadID, UploadStatusFailed)
+	if err != nil {
+		t.Fatalf("SetStatus() error = %v", err)
+	}
+
+	err = r.DeleteNamespace("logs")
+	if err != nil {
+		t.Errorf("DeleteNamespace() error = %v", err)
+	}
+
+	_, err = r.BeginUpload("logs", "a", 10, UploadOptions{})
+	if !errors.Is(err, ErrNamespaceNotFound) {
+		t.Errorf("BeginUpload() to deleted namespace error = %v, want %v", err, ErrNamespaceNotFound)
+	}
+}
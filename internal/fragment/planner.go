@@ -0,0 +1,61 @@
+package fragment
+
+import (
+	"errors"
+)
+
+type (
+	// Span is a byte range of the file, which is stored as a single fragment.
+	Span struct {
+		Offset int64
+		Length int64
+	}
+
+	// Planner computes fragment boundaries before upload: file is split to the desired number
+	// of fragments, unless there are fewer buckets or fragment size is out of limits.
+	Planner struct {
+		Fragments       int
+		MinFragmentSize int64
+		MaxFragmentSize int64
+	}
+)
+
+var (
+	ErrNoBuckets = errors.New("no buckets are available")
+)
+
+// Plan splits file of the size to fragments, only the last fragment could be smaller than others.
+// Empty file has no fragments.
+func (p Planner) Plan(size int64, buckets int) ([]Span, error) {
+	if size < 0 {
+		return nil, errors.New("negative file size")
+	}
+
+	// nothing is stored, so an empty file or a fully committed resume needs no buckets
+	if size == 0 {
+		return nil, nil
+	}
+
+	if buckets <= 0 {
+		return nil, ErrNoBuckets
+	}
+
+	fragments := int64(max(min(p.Fragments, buckets), 1))
+
+	fragmentSize := (size + fragments - 1) / fragments
+	fragmentSize = max(fragmentSize, p.MinFragmentSize)
+	if p.MaxFragmentSize > 0 {
+		fragmentSize = min(fragmentSize, p.MaxFragmentSize)
+	}
+	fragmentSize = max(fragmentSize, 1)
+
+	spans := make([]Span, 0, (size+fragmentSize-1)/fragmentSize)
+	for offset := int64(0); offset < size; offset += fragmentSize {
+		spans = append(spans, Span{
+			Offset: offset,
+			Length: min(fragmentSize, size-offset),
+		})
+	}
+
+	return spans, nil
+}
@@ -0,0 +1,122 @@
+package fragment
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlannerPlan(t *testing.T) {
+	planner := Planner{
+		Fragments:       6,
+		MinFragmentSize: 4,
+		MaxFragmentSize: 100,
+	}
+
+	tests := []struct {
+		name    string
+		planner Planner
+		size    int64
+		buckets int
+		want    []Span
+		wantErr error
+	}{
+		{
+			name:    "empty file",
+			planner: planner,
+			size:    0,
+			buckets: 6,
+			want:    nil,
+		},
+		{
+			name:    "empty file without buckets",
+			planner: planner,
+			size:    0,
+			buckets: 0,
+			want:    nil,
+		},
+		{
+			name:    "no buckets",
+			planner: planner,
+			size:    100,
+			buckets: 0,
+			wantErr: ErrNoBuckets,
+		},
+		{
+			name:    "tiny file",
+			planner: planner,
+			size:    5,
+			buckets: 6,
+			want:    []Span{{0, 4}, {4, 1}},
+		},
+		{
+			name:    "file smaller than min fragment size",
+			planner: planner,
+			size:    3,
+			buckets: 6,
+			want:    []Span{{0, 3}},
+		},
+		{
+			name:    "single byte without min fragment size",
+			planner: Planner{Fragments: 6},
+			size:    1,
+			buckets: 6,
+			want:    []Span{{0, 1}},
+		},
+		{
+			name:    "evenly divisible file",
+			planner: planner,
+			size:    60,
+			buckets: 6,
+			want:    []Span{{0, 10}, {10, 10}, {20, 10}, {30, 10}, {40, 10}, {50, 10}},
+		},
+		{
+			name:    "last fragment is smaller",
+			planner: planner,
+			size:    62,
+			buckets: 6,
+			want:    []Span{{0, 11}, {11, 11}, {22, 11}, {33, 11}, {44, 11}, {55, 7}},
+		},
+		{
+			name:    "fewer buckets than fragments",
+			planner: planner,
+			size:    60,
+			buckets: 2,
+			want:    []Span{{0, 30}, {30, 30}},
+		},
+		{
+			name:    "single bucket",
+			planner: planner,
+			size:    60,
+			buckets: 1,
+			want:    []Span{{0, 60}},
+		},
+		{
+			name:    "max fragment size",
+			planner: planner,
+			size:    1000,
+			buckets: 6,
+			want:    []Span{{0, 100}, {100, 100}, {200, 100}, {300, 100}, {400, 100}, {500, 100}, {600, 100}, {700, 100}, {800, 100}, {900, 100}},
+		},
+		{
+			name:    "max fragment size with fewer buckets",
+			planner: planner,
+			size:    250,
+			buckets: 1,
+			want:    []Span{{0, 100}, {100, 100}, {200, 50}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.planner.Plan(tt.size, tt.buckets)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Plan() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Plan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
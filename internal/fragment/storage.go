@@ -14,6 +14,10 @@ type (
 	}
 )
 
+var (
+	ErrFragmentExists = errors.New("fragment is already stored")
+)
+
 func NewFragmentsStorage(directory string) (*Storage, error) {
 	err := os.MkdirAll(directory, os.ModePerm)
 	if err != nil {
@@ -29,15 +33,30 @@ func (fs *Storage) fragmentPath(filename string, fragment int) string {
 	return path.Join(fs.directory, fmt.Sprintf("%s_%d.bin", filename, fragment))
 }
 
+// Put persists the fragment, it is synced to disk, when Put returns.
 func (fs *Storage) Put(filename string, fragment int, data []byte) error {
 	f := fs.fragmentPath(filename, fragment)
-	_, err := os.Stat(f)
-	if !errors.Is(err, filesystem.ErrNotExist) {
-		return errors.New("fragment is already stored")
+	file, err := os.OpenFile(f, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, filesystem.ErrExist) {
+		return ErrFragmentExists
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
 	}
 
-	err = os.WriteFile(f, data, 0644)
+	// partially written fragment is not kept
 	if err != nil {
+		os.Remove(f)
 		return err
 	}
 
@@ -53,6 +72,22 @@ func (fs *Storage) Get(filename string, fragment int) (*os.File, error) {
 	return file, nil
 }
 
+// Stat returns size of the stored fragment.
+func (fs *Storage) Stat(filename string, fragment int) (int64, error) {
+	info, err := os.Stat(fs.fragmentPath(filename, fragment))
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+// Delete removes the fragment. Deleting already removed fragment is not an error, so deletion can be retried.
 func (fs *Storage) Delete(filename string, fragment int) error {
-	return os.Remove(fs.fragmentPath(filename, fragment))
+	err := os.Remove(fs.fragmentPath(filename, fragment))
+	if errors.Is(err, filesystem.ErrNotExist) {
+		return nil
+	}
+
+	return err
 }
@@ -0,0 +1,184 @@
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+type (
+	Credentials struct {
+		lock     sync.RWMutex
+		certFile string
+		keyFile  string
+		caFile   string
+		modTimes []time.Time
+
+		cert  *tls.Certificate
+		roots *x509.CertPool
+	}
+)
+
+var (
+	ErrNoPeerCertificate = errors.New("peer has no TLS certificate")
+)
+
+func Load(certFile, keyFile, caFile string) (*Credentials, error) {
+	c := &Credentials{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+	}
+
+	_, err := c.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Reload keeps current credentials, when the files are invalid. New credentials are used by new connections.
+func (c *Credentials) Reload() (bool, error) {
+	var modTimes []time.Time
+	for _, path := range []string{c.certFile, c.keyFile, c.caFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+
+		modTimes = append(modTimes, info.ModTime())
+	}
+
+	c.lock.RLock()
+	modified := !slices.EqualFunc(modTimes, c.modTimes, time.Time.Equal)
+	c.lock.RUnlock()
+
+	if !modified {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	ca, err := os.ReadFile(c.caFile)
+	if err != nil {
+		return false, err
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return false, errors.New("CA file has no certificates")
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.cert = &cert
+	c.roots = roots
+	// modification times are kept only for loaded files, so failed rotation is retried by the next check
+	c.modTimes = modTimes
+
+	return true, nil
+}
+
+func (c *Credentials) certificate() *tls.Certificate {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.cert
+}
+
+func (c *Credentials) verify(chain []*x509.Certificate, host string, usage x509.ExtKeyUsage) error {
+	if len(chain) == 0 {
+		return ErrNoPeerCertificate
+	}
+
+	c.lock.RLock()
+	roots := c.roots
+	c.lock.RUnlock()
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       host,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+
+	return err
+}
+
+// ServerCredentials accepts any client certificate issued by the CA, when clientHost is empty.
+func (c *Credentials) ServerCredentials(clientHost string) credentials.TransportCredentials {
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return c.certificate(), nil
+		},
+		// client certificate is verified below with the current CA, since the CA could be reloaded
+		ClientAuth: tls.RequireAnyClientCert,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return c.verify(cs.PeerCertificates, clientHost, x509.ExtKeyUsageClientAuth)
+		},
+	})
+}
+
+func (c *Credentials) ClientCredentials(host string) credentials.TransportCredentials {
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return c.certificate(), nil
+		},
+		// server certificate is verified below with the current CA, since the CA could be reloaded
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return c.verify(cs.PeerCertificates, host, x509.ExtKeyUsageServerAuth)
+		},
+	})
+}
+
+func PeerCertificate(ctx context.Context) (*x509.Certificate, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoPeerCertificate
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return nil, ErrNoPeerCertificate
+	}
+
+	return info.State.PeerCertificates[0], nil
+}
+
+// VerifyPeerAddress prevents a server from registering or deregistering other address than its own.
+func VerifyPeerAddress(ctx context.Context, address string) error {
+	cert, err := PeerCertificate(ctx)
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	return cert.VerifyHostname(host)
+}
@@ -0,0 +1,365 @@
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// testCert is PEM encoded certificate with its key.
+type testCert struct {
+	cert []byte
+	key  []byte
+}
+
+var (
+	serialNumber int64
+
+	bothUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+)
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serialNumber++
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serialNumber),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns the certificate for the host, which is DNS name or IP address.
+func (ca *testCA) issue(t *testing.T, host string, usages []x509.ExtKeyUsage) testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serialNumber++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serialNumber),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usages,
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testCert{
+		cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		key:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c testCert) chain(t *testing.T) []*x509.Certificate {
+	t.Helper()
+
+	block, _ := pem.Decode(c.cert)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []*x509.Certificate{cert}
+}
+
+// writeCredentials writes certificate, key and CA files to the directory with the modification time
+// and returns their paths.
+func writeCredentials(t *testing.T, dir string, cert testCert, ca []byte, modTime time.Time) (string, string, string) {
+	t.Helper()
+
+	files := []struct {
+		path    string
+		content []byte
+	}{
+		{path: filepath.Join(dir, "cert.pem"), content: cert.cert},
+		{path: filepath.Join(dir, "key.pem"), content: cert.key},
+		{path: filepath.Join(dir, "ca.pem"), content: ca},
+	}
+
+	for _, f := range files {
+		err := os.WriteFile(f.path, f.content, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.Chtimes(f.path, modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return files[0].path, files[1].path, files[2].path
+}
+
+func loadCredentials(t *testing.T, cert testCert, ca []byte) *Credentials {
+	t.Helper()
+
+	c, err := Load(writeCredentials(t, t.TempDir(), cert, ca, time.Now()))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	return c
+}
+
+func TestVerify(t *testing.T) {
+	ca := newTestCA(t, "cluster")
+	other := newTestCA(t, "other")
+	c := loadCredentials(t, ca.issue(t, "api.local", bothUsages), ca.pem)
+
+	tests := []struct {
+		name    string
+		cert    testCert
+		host    string
+		usage   x509.ExtKeyUsage
+		wantErr bool
+	}{
+		{name: "client certificate", cert: ca.issue(t, "api.local", bothUsages), usage: x509.ExtKeyUsageClientAuth},
+		{name: "client certificate for the host", cert: ca.issue(t, "api.local", bothUsages), host: "api.local", usage: x509.ExtKeyUsageClientAuth},
+		{name: "server certificate for IP address", cert: ca.issue(t, "10.0.0.1", bothUsages), host: "10.0.0.1", usage: x509.ExtKeyUsageServerAuth},
+		{name: "another CA", cert: other.issue(t, "api.local", bothUsages), host: "api.local", usage: x509.ExtKeyUsageClientAuth, wantErr: true},
+		{name: "server only usage", cert: ca.issue(t, "api.local", []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}), usage: x509.ExtKeyUsageClientAuth, wantErr: true},
+		{name: "client only usage", cert: ca.issue(t, "bucket.local", []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}), host: "bucket.local", usage: x509.ExtKeyUsageServerAuth, wantErr: true},
+		{name: "another host", cert: ca.issue(t, "bucket.local", bothUsages), host: "api.local", usage: x509.ExtKeyUsageClientAuth, wantErr: true},
+		{name: "another IP address", cert: ca.issue(t, "10.0.0.2", bothUsages), host: "10.0.0.1", usage: x509.ExtKeyUsageServerAuth, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.verify(tt.cert.chain(t), tt.host, tt.usage)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	err := c.verify(nil, "", x509.ExtKeyUsageClientAuth)
+	if !errors.Is(err, ErrNoPeerCertificate) {
+		t.Errorf("verify() without certificate error = %v, want %v", err, ErrNoPeerCertificate)
+	}
+}
+
+// handshake connects the client to the server, which accepts only clients of the host, and returns
+// the error of the server handshake.
+func handshake(t *testing.T, server, client *Credentials, serverHost, clientHost string) error {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+
+		_, _, err = server.ServerCredentials(clientHost).ServerHandshake(conn)
+		serverErr <- err
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// TLS 1.3 client completes the handshake before the server verifies its certificate, so only the server is checked
+	client.ClientCredentials(serverHost).ClientHandshake(ctx, serverHost, conn)
+	conn.Close()
+
+	return <-serverErr
+}
+
+func TestServerCredentials(t *testing.T) {
+	ca := newTestCA(t, "cluster")
+	other := newTestCA(t, "other")
+	bucket := loadCredentials(t, ca.issue(t, "bucket-1.local", bothUsages), ca.pem)
+
+	tests := []struct {
+		name       string
+		client     testCert
+		clientHost string
+		wantErr    bool
+	}{
+		{name: "API server", client: ca.issue(t, "api.local", bothUsages), clientHost: "api.local"},
+		{name: "another bucket server", client: ca.issue(t, "bucket-2.local", bothUsages), clientHost: "api.local", wantErr: true},
+		{name: "any client of the CA", client: ca.issue(t, "bucket-2.local", bothUsages)},
+		{name: "client of another CA", client: other.issue(t, "api.local", bothUsages), clientHost: "api.local", wantErr: true},
+		{name: "server only usage", client: ca.issue(t, "api.local", []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}), clientHost: "api.local", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// client trusts the cluster CA, so the server certificate is accepted
+			client := loadCredentials(t, tt.client, ca.pem)
+
+			err := handshake(t, bucket, client, "bucket-1.local", tt.clientHost)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("server handshake error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+
+	oldCA := newTestCA(t, "old")
+	newCA := newTestCA(t, "new")
+
+	c, err := Load(writeCredentials(t, dir, oldCA.issue(t, "api.local", bothUsages), oldCA.pem, start))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	oldPeer := oldCA.issue(t, "bucket.local", bothUsages).chain(t)
+	newPeer := newCA.issue(t, "bucket.local", bothUsages).chain(t)
+
+	// every step rewrites the files, when CA is set, and checks which peers are accepted after the reload
+	tests := []struct {
+		name         string
+		ca           *testCA
+		cert         *testCert
+		modTime      time.Time
+		wantReloaded bool
+		wantErr      bool
+		wantOld      bool
+		wantNew      bool
+	}{
+		{
+			name:    "files are not modified",
+			wantOld: true,
+		},
+		{
+			name:    "invalid certificate keeps credentials",
+			ca:      newCA,
+			cert:    &testCert{cert: []byte("invalid"), key: []byte("invalid")},
+			modTime: start.Add(time.Minute),
+			wantErr: true,
+			wantOld: true,
+		},
+		{
+			name:    "invalid files are read again",
+			wantErr: true,
+			wantOld: true,
+		},
+		{
+			name:         "rotated CA and certificate",
+			ca:           newCA,
+			modTime:      start.Add(2 * time.Minute),
+			wantReloaded: true,
+			wantNew:      true,
+		},
+		{
+			name:    "rotated files are not read again",
+			wantNew: true,
+		},
+	}
+
+	for _, tt := range tests {
+		var wantCert []byte
+		if tt.ca != nil {
+			cert := tt.ca.issue(t, "api.local", bothUsages)
+			if tt.cert != nil {
+				cert = *tt.cert
+			}
+			wantCert = cert.cert
+
+			writeCredentials(t, dir, cert, tt.ca.pem, tt.modTime)
+		}
+
+		reloaded, err := c.Reload()
+		if reloaded != tt.wantReloaded || (err != nil) != tt.wantErr {
+			t.Fatalf("%s: Reload() = %v, %v, want %v, wantErr %v", tt.name, reloaded, err, tt.wantReloaded, tt.wantErr)
+		}
+
+		if err := c.verify(oldPeer, "bucket.local", x509.ExtKeyUsageServerAuth); (err == nil) != tt.wantOld {
+			t.Errorf("%s: peer of old CA verify() error = %v, want accepted %v", tt.name, err, tt.wantOld)
+		}
+		if err := c.verify(newPeer, "bucket.local", x509.ExtKeyUsageServerAuth); (err == nil) != tt.wantNew {
+			t.Errorf("%s: peer of new CA verify() error = %v, want accepted %v", tt.name, err, tt.wantNew)
+		}
+
+		if tt.wantReloaded {
+			block, _ := pem.Decode(wantCert)
+			if got := c.certificate().Certificate[0]; string(got) != string(block.Bytes) {
+				t.Errorf("%s: certificate is not rotated", tt.name)
+			}
+		}
+	}
+
+	_, err = Load(filepath.Join(dir, "missing.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem"))
+	if err == nil {
+		t.Error("Load() of missing file error = nil, want error")
+	}
+}
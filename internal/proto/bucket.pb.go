@@ -20,12 +20,143 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type WsProtocolVersion int32
+
+const (
+	WsProtocolVersion_WS_PROTOCOL_PLAIN  WsProtocolVersion = 0 // binary chunks, terminated by an empty one
+	WsProtocolVersion_WS_PROTOCOL_FRAMED WsProtocolVersion = 1 // sequence numbered chunks, acknowledged by the server
+)
+
+// Enum value maps for WsProtocolVersion.
+var (
+	WsProtocolVersion_name = map[int32]string{
+		0: "WS_PROTOCOL_PLAIN",
+		1: "WS_PROTOCOL_FRAMED",
+	}
+	WsProtocolVersion_value = map[string]int32{
+		"WS_PROTOCOL_PLAIN":  0,
+		"WS_PROTOCOL_FRAMED": 1,
+	}
+)
+
+func (x WsProtocolVersion) Enum() *WsProtocolVersion {
+	p := new(WsProtocolVersion)
+	*p = x
+	return p
+}
+
+func (x WsProtocolVersion) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WsProtocolVersion) Descriptor() protoreflect.EnumDescriptor {
+	return file_internal_proto_bucket_proto_enumTypes[0].Descriptor()
+}
+
+func (WsProtocolVersion) Type() protoreflect.EnumType {
+	return &file_internal_proto_bucket_proto_enumTypes[0]
+}
+
+func (x WsProtocolVersion) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WsProtocolVersion.Descriptor instead.
+func (WsProtocolVersion) EnumDescriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{0}
+}
+
+// WsErrorCode is reported in error frames and as 4000 + code WebSocket close code.
+type WsErrorCode int32
+
+const (
+	WsErrorCode_WS_ERROR_NONE                WsErrorCode = 0
+	WsErrorCode_WS_ERROR_INTERNAL            WsErrorCode = 1
+	WsErrorCode_WS_ERROR_BAD_REQUEST         WsErrorCode = 2
+	WsErrorCode_WS_ERROR_NOT_FOUND           WsErrorCode = 3
+	WsErrorCode_WS_ERROR_NO_CAPACITY         WsErrorCode = 4
+	WsErrorCode_WS_ERROR_FILE_EXISTS         WsErrorCode = 5
+	WsErrorCode_WS_ERROR_CHECKSUM_MISMATCH   WsErrorCode = 6
+	WsErrorCode_WS_ERROR_QUOTA_EXCEEDED      WsErrorCode = 7
+	WsErrorCode_WS_ERROR_UPLOAD_ACTIVE       WsErrorCode = 8
+	WsErrorCode_WS_ERROR_NAMESPACE_NOT_EMPTY WsErrorCode = 9
+	WsErrorCode_WS_ERROR_UNAUTHENTICATED     WsErrorCode = 10
+	WsErrorCode_WS_ERROR_FORBIDDEN           WsErrorCode = 11
+)
+
+// Enum value maps for WsErrorCode.
+var (
+	WsErrorCode_name = map[int32]string{
+		0:  "WS_ERROR_NONE",
+		1:  "WS_ERROR_INTERNAL",
+		2:  "WS_ERROR_BAD_REQUEST",
+		3:  "WS_ERROR_NOT_FOUND",
+		4:  "WS_ERROR_NO_CAPACITY",
+		5:  "WS_ERROR_FILE_EXISTS",
+		6:  "WS_ERROR_CHECKSUM_MISMATCH",
+		7:  "WS_ERROR_QUOTA_EXCEEDED",
+		8:  "WS_ERROR_UPLOAD_ACTIVE",
+		9:  "WS_ERROR_NAMESPACE_NOT_EMPTY",
+		10: "WS_ERROR_UNAUTHENTICATED",
+		11: "WS_ERROR_FORBIDDEN",
+	}
+	WsErrorCode_value = map[string]int32{
+		"WS_ERROR_NONE":                0,
+		"WS_ERROR_INTERNAL":            1,
+		"WS_ERROR_BAD_REQUEST":         2,
+		"WS_ERROR_NOT_FOUND":           3,
+		"WS_ERROR_NO_CAPACITY":         4,
+		"WS_ERROR_FILE_EXISTS":         5,
+		"WS_ERROR_CHECKSUM_MISMATCH":   6,
+		"WS_ERROR_QUOTA_EXCEEDED":      7,
+		"WS_ERROR_UPLOAD_ACTIVE":       8,
+		"WS_ERROR_NAMESPACE_NOT_EMPTY": 9,
+		"WS_ERROR_UNAUTHENTICATED":     10,
+		"WS_ERROR_FORBIDDEN":           11,
+	}
+)
+
+func (x WsErrorCode) Enum() *WsErrorCode {
+	p := new(WsErrorCode)
+	*p = x
+	return p
+}
+
+func (x WsErrorCode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WsErrorCode) Descriptor() protoreflect.EnumDescriptor {
+	return file_internal_proto_bucket_proto_enumTypes[1].Descriptor()
+}
+
+func (WsErrorCode) Type() protoreflect.EnumType {
+	return &file_internal_proto_bucket_proto_enumTypes[1]
+}
+
+func (x WsErrorCode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WsErrorCode.Descriptor instead.
+func (WsErrorCode) EnumDescriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{1}
+}
+
 type WsFileInfo struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Size int64 `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	Size        int64             `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	Resumable   bool              `protobuf:"varint,2,opt,name=resumable,proto3" json:"resumable,omitempty"`
+	UploadId    string            `protobuf:"bytes,3,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"` // interrupted upload to resume
+	Version     WsProtocolVersion `protobuf:"varint,4,opt,name=version,proto3,enum=bucket.WsProtocolVersion" json:"version,omitempty"`
+	Exclusive   bool              `protobuf:"varint,5,opt,name=exclusive,proto3" json:"exclusive,omitempty"` // fail, if the file already exists
+	Checksum    string            `protobuf:"bytes,6,opt,name=checksum,proto3" json:"checksum,omitempty"`    // expected SHA-256 of the file, verified when upload is complete
+	Codec       string            `protobuf:"bytes,7,opt,name=codec,proto3" json:"codec,omitempty"`          // compression codec of stored fragments: none, gzip or zstd, server default when empty
+	ContentType string            `protobuf:"bytes,8,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Metadata    map[string]string `protobuf:"bytes,9,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"` // user metadata, keys are case-insensitive
 }
 
 func (x *WsFileInfo) Reset() {
@@ -67,16 +198,74 @@ func (x *WsFileInfo) GetSize() int64 {
 	return 0
 }
 
-type RegisterBucketRequest struct {
+func (x *WsFileInfo) GetResumable() bool {
+	if x != nil {
+		return x.Resumable
+	}
+	return false
+}
+
+func (x *WsFileInfo) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+func (x *WsFileInfo) GetVersion() WsProtocolVersion {
+	if x != nil {
+		return x.Version
+	}
+	return WsProtocolVersion_WS_PROTOCOL_PLAIN
+}
+
+func (x *WsFileInfo) GetExclusive() bool {
+	if x != nil {
+		return x.Exclusive
+	}
+	return false
+}
+
+func (x *WsFileInfo) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *WsFileInfo) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *WsFileInfo) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *WsFileInfo) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type WsError struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Code    WsErrorCode `protobuf:"varint,1,opt,name=code,proto3,enum=bucket.WsErrorCode" json:"code,omitempty"`
+	Reason  string      `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"` // machine-readable code name, e.g. no_capacity
+	Message string      `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
 }
 
-func (x *RegisterBucketRequest) Reset() {
-	*x = RegisterBucketRequest{}
+func (x *WsError) Reset() {
+	*x = WsError{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_internal_proto_bucket_proto_msgTypes[1]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -84,13 +273,13 @@ func (x *RegisterBucketRequest) Reset() {
 	}
 }
 
-func (x *RegisterBucketRequest) String() string {
+func (x *WsError) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterBucketRequest) ProtoMessage() {}
+func (*WsError) ProtoMessage() {}
 
-func (x *RegisterBucketRequest) ProtoReflect() protoreflect.Message {
+func (x *WsError) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_bucket_proto_msgTypes[1]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -102,26 +291,45 @@ func (x *RegisterBucketRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterBucketRequest.ProtoReflect.Descriptor instead.
-func (*RegisterBucketRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use WsError.ProtoReflect.Descriptor instead.
+func (*WsError) Descriptor() ([]byte, []int) {
 	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *RegisterBucketRequest) GetAddress() string {
+func (x *WsError) GetCode() WsErrorCode {
 	if x != nil {
-		return x.Address
+		return x.Code
+	}
+	return WsErrorCode_WS_ERROR_NONE
+}
+
+func (x *WsError) GetReason() string {
+	if x != nil {
+		return x.Reason
 	}
 	return ""
 }
 
-type RegisterBucketResponse struct {
+func (x *WsError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type WsUploadSession struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	UploadId string            `protobuf:"bytes,1,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"`
+	Offset   int64             `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"` // bytes already stored, upload continues from this offset
+	Version  WsProtocolVersion `protobuf:"varint,3,opt,name=version,proto3,enum=bucket.WsProtocolVersion" json:"version,omitempty"`
+	Window   uint32            `protobuf:"varint,4,opt,name=window,proto3" json:"window,omitempty"` // maximum number of chunks, sent ahead of server acknowledgement
 }
 
-func (x *RegisterBucketResponse) Reset() {
-	*x = RegisterBucketResponse{}
+func (x *WsUploadSession) Reset() {
+	*x = WsUploadSession{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_internal_proto_bucket_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -129,13 +337,13 @@ func (x *RegisterBucketResponse) Reset() {
 	}
 }
 
-func (x *RegisterBucketResponse) String() string {
+func (x *WsUploadSession) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterBucketResponse) ProtoMessage() {}
+func (*WsUploadSession) ProtoMessage() {}
 
-func (x *RegisterBucketResponse) ProtoReflect() protoreflect.Message {
+func (x *WsUploadSession) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_bucket_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -147,21 +355,50 @@ func (x *RegisterBucketResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterBucketResponse.ProtoReflect.Descriptor instead.
-func (*RegisterBucketResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use WsUploadSession.ProtoReflect.Descriptor instead.
+func (*WsUploadSession) Descriptor() ([]byte, []int) {
 	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{2}
 }
 
-type Chunk struct {
+func (x *WsUploadSession) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+func (x *WsUploadSession) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *WsUploadSession) GetVersion() WsProtocolVersion {
+	if x != nil {
+		return x.Version
+	}
+	return WsProtocolVersion_WS_PROTOCOL_PLAIN
+}
+
+func (x *WsUploadSession) GetWindow() uint32 {
+	if x != nil {
+		return x.Window
+	}
+	return 0
+}
+
+type WsAck struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	NextSeq   uint64 `protobuf:"varint,1,opt,name=next_seq,json=nextSeq,proto3" json:"next_seq,omitempty"` // all chunks before this sequence number are processed
+	Committed int64  `protobuf:"varint,2,opt,name=committed,proto3" json:"committed,omitempty"`            // bytes durably stored on bucket servers
 }
 
-func (x *Chunk) Reset() {
-	*x = Chunk{}
+func (x *WsAck) Reset() {
+	*x = WsAck{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_internal_proto_bucket_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -169,13 +406,13 @@ func (x *Chunk) Reset() {
 	}
 }
 
-func (x *Chunk) String() string {
+func (x *WsAck) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Chunk) ProtoMessage() {}
+func (*WsAck) ProtoMessage() {}
 
-func (x *Chunk) ProtoReflect() protoreflect.Message {
+func (x *WsAck) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_bucket_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -187,30 +424,38 @@ func (x *Chunk) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Chunk.ProtoReflect.Descriptor instead.
-func (*Chunk) Descriptor() ([]byte, []int) {
+// Deprecated: Use WsAck.ProtoReflect.Descriptor instead.
+func (*WsAck) Descriptor() ([]byte, []int) {
 	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *Chunk) GetData() []byte {
+func (x *WsAck) GetNextSeq() uint64 {
 	if x != nil {
-		return x.Data
+		return x.NextSeq
 	}
-	return nil
+	return 0
 }
 
-type UploadChunk struct {
+func (x *WsAck) GetCommitted() int64 {
+	if x != nil {
+		return x.Committed
+	}
+	return 0
+}
+
+type WsUploadStatus struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	Fragment uint32 `protobuf:"varint,2,opt,name=fragment,proto3" json:"fragment,omitempty"`
-	Chunk    *Chunk `protobuf:"bytes,3,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	Status   string `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Size     int64  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Checksum string `protobuf:"bytes,3,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	Error    string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
 }
 
-func (x *UploadChunk) Reset() {
-	*x = UploadChunk{}
+func (x *WsUploadStatus) Reset() {
+	*x = WsUploadStatus{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_internal_proto_bucket_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -218,13 +463,13 @@ func (x *UploadChunk) Reset() {
 	}
 }
 
-func (x *UploadChunk) String() string {
+func (x *WsUploadStatus) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UploadChunk) ProtoMessage() {}
+func (*WsUploadStatus) ProtoMessage() {}
 
-func (x *UploadChunk) ProtoReflect() protoreflect.Message {
+func (x *WsUploadStatus) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_bucket_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -236,40 +481,51 @@ func (x *UploadChunk) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UploadChunk.ProtoReflect.Descriptor instead.
-func (*UploadChunk) Descriptor() ([]byte, []int) {
+// Deprecated: Use WsUploadStatus.ProtoReflect.Descriptor instead.
+func (*WsUploadStatus) Descriptor() ([]byte, []int) {
 	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *UploadChunk) GetFilename() string {
+func (x *WsUploadStatus) GetStatus() string {
 	if x != nil {
-		return x.Filename
+		return x.Status
 	}
 	return ""
 }
 
-func (x *UploadChunk) GetFragment() uint32 {
+func (x *WsUploadStatus) GetSize() int64 {
 	if x != nil {
-		return x.Fragment
+		return x.Size
 	}
 	return 0
 }
 
-func (x *UploadChunk) GetChunk() *Chunk {
+func (x *WsUploadStatus) GetChecksum() string {
 	if x != nil {
-		return x.Chunk
+		return x.Checksum
 	}
-	return nil
+	return ""
 }
 
-type UploadResponse struct {
+func (x *WsUploadStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type WsServerMessage struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	Ack    *WsAck          `protobuf:"bytes,1,opt,name=ack,proto3" json:"ack,omitempty"`
+	Status *WsUploadStatus `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Error  *WsError        `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 }
 
-func (x *UploadResponse) Reset() {
-	*x = UploadResponse{}
+func (x *WsServerMessage) Reset() {
+	*x = WsServerMessage{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_internal_proto_bucket_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -277,13 +533,13 @@ func (x *UploadResponse) Reset() {
 	}
 }
 
-func (x *UploadResponse) String() string {
+func (x *WsServerMessage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UploadResponse) ProtoMessage() {}
+func (*WsServerMessage) ProtoMessage() {}
 
-func (x *UploadResponse) ProtoReflect() protoreflect.Message {
+func (x *WsServerMessage) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_bucket_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -295,22 +551,44 @@ func (x *UploadResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UploadResponse.ProtoReflect.Descriptor instead.
-func (*UploadResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use WsServerMessage.ProtoReflect.Descriptor instead.
+func (*WsServerMessage) Descriptor() ([]byte, []int) {
 	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{5}
 }
 
-type DownloadRequest struct {
+func (x *WsServerMessage) GetAck() *WsAck {
+	if x != nil {
+		return x.Ack
+	}
+	return nil
+}
+
+func (x *WsServerMessage) GetStatus() *WsUploadStatus {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+func (x *WsServerMessage) GetError() *WsError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type FragmentDeleteFailure struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	Fragment uint32 `protobuf:"varint,2,opt,name=fragment,proto3" json:"fragment,omitempty"`
+	Fragment uint32 `protobuf:"varint,1,opt,name=fragment,proto3" json:"fragment,omitempty"`
+	Address  string `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Error    string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 }
 
-func (x *DownloadRequest) Reset() {
-	*x = DownloadRequest{}
+func (x *FragmentDeleteFailure) Reset() {
+	*x = FragmentDeleteFailure{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_internal_proto_bucket_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -318,13 +596,13 @@ func (x *DownloadRequest) Reset() {
 	}
 }
 
-func (x *DownloadRequest) String() string {
+func (x *FragmentDeleteFailure) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DownloadRequest) ProtoMessage() {}
+func (*FragmentDeleteFailure) ProtoMessage() {}
 
-func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
+func (x *FragmentDeleteFailure) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_bucket_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -336,36 +614,46 @@ func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DownloadRequest.ProtoReflect.Descriptor instead.
-func (*DownloadRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use FragmentDeleteFailure.ProtoReflect.Descriptor instead.
+func (*FragmentDeleteFailure) Descriptor() ([]byte, []int) {
 	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *DownloadRequest) GetFilename() string {
+func (x *FragmentDeleteFailure) GetFragment() uint32 {
 	if x != nil {
-		return x.Filename
+		return x.Fragment
+	}
+	return 0
+}
+
+func (x *FragmentDeleteFailure) GetAddress() string {
+	if x != nil {
+		return x.Address
 	}
 	return ""
 }
 
-func (x *DownloadRequest) GetFragment() uint32 {
+func (x *FragmentDeleteFailure) GetError() string {
 	if x != nil {
-		return x.Fragment
+		return x.Error
 	}
-	return 0
+	return ""
 }
 
-type DeleteFragmentRequest struct {
+type DeleteFileReport struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	Fragment uint32 `protobuf:"varint,2,opt,name=fragment,proto3" json:"fragment,omitempty"`
+	Filename  string                   `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Fragments uint32                   `protobuf:"varint,2,opt,name=fragments,proto3" json:"fragments,omitempty"`
+	Deleted   uint32                   `protobuf:"varint,3,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Deferred  bool                     `protobuf:"varint,4,opt,name=deferred,proto3" json:"deferred,omitempty"`
+	Failures  []*FragmentDeleteFailure `protobuf:"bytes,5,rep,name=failures,proto3" json:"failures,omitempty"`
 }
 
-func (x *DeleteFragmentRequest) Reset() {
-	*x = DeleteFragmentRequest{}
+func (x *DeleteFileReport) Reset() {
+	*x = DeleteFileReport{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_internal_proto_bucket_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -373,13 +661,13 @@ func (x *DeleteFragmentRequest) Reset() {
 	}
 }
 
-func (x *DeleteFragmentRequest) String() string {
+func (x *DeleteFileReport) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteFragmentRequest) ProtoMessage() {}
+func (*DeleteFileReport) ProtoMessage() {}
 
-func (x *DeleteFragmentRequest) ProtoReflect() protoreflect.Message {
+func (x *DeleteFileReport) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_bucket_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -391,33 +679,61 @@ func (x *DeleteFragmentRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteFragmentRequest.ProtoReflect.Descriptor instead.
-func (*DeleteFragmentRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use DeleteFileReport.ProtoReflect.Descriptor instead.
+func (*DeleteFileReport) Descriptor() ([]byte, []int) {
 	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *DeleteFragmentRequest) GetFilename() string {
+func (x *DeleteFileReport) GetFilename() string {
 	if x != nil {
 		return x.Filename
 	}
 	return ""
 }
 
-func (x *DeleteFragmentRequest) GetFragment() uint32 {
+func (x *DeleteFileReport) GetFragments() uint32 {
 	if x != nil {
-		return x.Fragment
+		return x.Fragments
 	}
 	return 0
 }
 
-type DeleteFragmentResponse struct {
+func (x *DeleteFileReport) GetDeleted() uint32 {
+	if x != nil {
+		return x.Deleted
+	}
+	return 0
+}
+
+func (x *DeleteFileReport) GetDeferred() bool {
+	if x != nil {
+		return x.Deferred
+	}
+	return false
+}
+
+func (x *DeleteFileReport) GetFailures() []*FragmentDeleteFailure {
+	if x != nil {
+		return x.Failures
+	}
+	return nil
+}
+
+type FragmentInfo struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	Fragment uint32   `protobuf:"varint,1,opt,name=fragment,proto3" json:"fragment,omitempty"`
+	Address  string   `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Size     int64    `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Checksum string   `protobuf:"bytes,4,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	Offset   int64    `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`    // offset of the fragment in the file
+	Replicas []string `protobuf:"bytes,6,rep,name=replicas,proto3" json:"replicas,omitempty"` // bucket servers, which store the fragment
 }
 
-func (x *DeleteFragmentResponse) Reset() {
-	*x = DeleteFragmentResponse{}
+func (x *FragmentInfo) Reset() {
+	*x = FragmentInfo{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_internal_proto_bucket_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -425,13 +741,13 @@ func (x *DeleteFragmentResponse) Reset() {
 	}
 }
 
-func (x *DeleteFragmentResponse) String() string {
+func (x *FragmentInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteFragmentResponse) ProtoMessage() {}
+func (*FragmentInfo) ProtoMessage() {}
 
-func (x *DeleteFragmentResponse) ProtoReflect() protoreflect.Message {
+func (x *FragmentInfo) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_proto_bucket_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -443,66 +759,1423 @@ func (x *DeleteFragmentResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteFragmentResponse.ProtoReflect.Descriptor instead.
-func (*DeleteFragmentResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use FragmentInfo.ProtoReflect.Descriptor instead.
+func (*FragmentInfo) Descriptor() ([]byte, []int) {
 	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{8}
 }
 
-var File_internal_proto_bucket_proto protoreflect.FileDescriptor
+func (x *FragmentInfo) GetFragment() uint32 {
+	if x != nil {
+		return x.Fragment
+	}
+	return 0
+}
+
+func (x *FragmentInfo) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *FragmentInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FragmentInfo) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *FragmentInfo) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *FragmentInfo) GetReplicas() []string {
+	if x != nil {
+		return x.Replicas
+	}
+	return nil
+}
+
+type FileStat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Filename    string            `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Size        int64             `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Status      string            `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Fragments   uint32            `protobuf:"varint,4,opt,name=fragments,proto3" json:"fragments,omitempty"`
+	Placement   []*FragmentInfo   `protobuf:"bytes,5,rep,name=placement,proto3" json:"placement,omitempty"`
+	Checksum    string            `protobuf:"bytes,6,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	CreatedAt   string            `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	CompletedAt string            `protobuf:"bytes,8,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
+	Codec       string            `protobuf:"bytes,9,opt,name=codec,proto3" json:"codec,omitempty"`
+	Encrypted   bool              `protobuf:"varint,10,opt,name=encrypted,proto3" json:"encrypted,omitempty"`
+	ContentType string            `protobuf:"bytes,11,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Metadata    map[string]string `protobuf:"bytes,12,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Namespace   string            `protobuf:"bytes,13,opt,name=namespace,proto3" json:"namespace,omitempty"`
+}
+
+func (x *FileStat) Reset() {
+	*x = FileStat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileStat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileStat) ProtoMessage() {}
+
+func (x *FileStat) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileStat.ProtoReflect.Descriptor instead.
+func (*FileStat) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *FileStat) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *FileStat) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileStat) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *FileStat) GetFragments() uint32 {
+	if x != nil {
+		return x.Fragments
+	}
+	return 0
+}
+
+func (x *FileStat) GetPlacement() []*FragmentInfo {
+	if x != nil {
+		return x.Placement
+	}
+	return nil
+}
+
+func (x *FileStat) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *FileStat) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *FileStat) GetCompletedAt() string {
+	if x != nil {
+		return x.CompletedAt
+	}
+	return ""
+}
+
+func (x *FileStat) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *FileStat) GetEncrypted() bool {
+	if x != nil {
+		return x.Encrypted
+	}
+	return false
+}
+
+func (x *FileStat) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *FileStat) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *FileStat) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type FileList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Files      []*FileStat `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	NextCursor string      `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+}
+
+func (x *FileList) Reset() {
+	*x = FileList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileList) ProtoMessage() {}
+
+func (x *FileList) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileList.ProtoReflect.Descriptor instead.
+func (*FileList) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FileList) GetFiles() []*FileStat {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *FileList) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type NamespaceInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name        string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Replication uint32 `protobuf:"varint,2,opt,name=replication,proto3" json:"replication,omitempty"` // bucket servers storing every fragment
+	Quota       int64  `protobuf:"varint,3,opt,name=quota,proto3" json:"quota,omitempty"`             // total size of files in bytes, unlimited when 0
+	Retention   string `protobuf:"bytes,4,opt,name=retention,proto3" json:"retention,omitempty"`      // how long files are kept, e.g. 720h, forever when empty
+	CreatedAt   string `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Files       uint32 `protobuf:"varint,6,opt,name=files,proto3" json:"files,omitempty"`
+	Size        int64  `protobuf:"varint,7,opt,name=size,proto3" json:"size,omitempty"` // total size of files
+}
+
+func (x *NamespaceInfo) Reset() {
+	*x = NamespaceInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NamespaceInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespaceInfo) ProtoMessage() {}
+
+func (x *NamespaceInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespaceInfo.ProtoReflect.Descriptor instead.
+func (*NamespaceInfo) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *NamespaceInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NamespaceInfo) GetReplication() uint32 {
+	if x != nil {
+		return x.Replication
+	}
+	return 0
+}
+
+func (x *NamespaceInfo) GetQuota() int64 {
+	if x != nil {
+		return x.Quota
+	}
+	return 0
+}
+
+func (x *NamespaceInfo) GetRetention() string {
+	if x != nil {
+		return x.Retention
+	}
+	return ""
+}
+
+func (x *NamespaceInfo) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *NamespaceInfo) GetFiles() uint32 {
+	if x != nil {
+		return x.Files
+	}
+	return 0
+}
+
+func (x *NamespaceInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type NamespaceList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Namespaces []*NamespaceInfo `protobuf:"bytes,1,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+}
+
+func (x *NamespaceList) Reset() {
+	*x = NamespaceList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NamespaceList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespaceList) ProtoMessage() {}
+
+func (x *NamespaceList) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespaceList.ProtoReflect.Descriptor instead.
+func (*NamespaceList) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *NamespaceList) GetNamespaces() []*NamespaceInfo {
+	if x != nil {
+		return x.Namespaces
+	}
+	return nil
+}
+
+type KeyRotationReport struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	KeyId     string `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"` // master key, which wraps data keys now
+	Rewrapped uint32 `protobuf:"varint,2,opt,name=rewrapped,proto3" json:"rewrapped,omitempty"`
+}
+
+func (x *KeyRotationReport) Reset() {
+	*x = KeyRotationReport{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *KeyRotationReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyRotationReport) ProtoMessage() {}
+
+func (x *KeyRotationReport) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyRotationReport.ProtoReflect.Descriptor instead.
+func (*KeyRotationReport) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *KeyRotationReport) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *KeyRotationReport) GetRewrapped() uint32 {
+	if x != nil {
+		return x.Rewrapped
+	}
+	return 0
+}
+
+type RegisterBucketRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+}
+
+func (x *RegisterBucketRequest) Reset() {
+	*x = RegisterBucketRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RegisterBucketRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterBucketRequest) ProtoMessage() {}
+
+func (x *RegisterBucketRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterBucketRequest.ProtoReflect.Descriptor instead.
+func (*RegisterBucketRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RegisterBucketRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type RegisterBucketResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *RegisterBucketResponse) Reset() {
+	*x = RegisterBucketResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RegisterBucketResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterBucketResponse) ProtoMessage() {}
+
+func (x *RegisterBucketResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterBucketResponse.ProtoReflect.Descriptor instead.
+func (*RegisterBucketResponse) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{15}
+}
+
+type DeregisterBucketRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+}
+
+func (x *DeregisterBucketRequest) Reset() {
+	*x = DeregisterBucketRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeregisterBucketRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeregisterBucketRequest) ProtoMessage() {}
+
+func (x *DeregisterBucketRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeregisterBucketRequest.ProtoReflect.Descriptor instead.
+func (*DeregisterBucketRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *DeregisterBucketRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type DeregisterBucketResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *DeregisterBucketResponse) Reset() {
+	*x = DeregisterBucketResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeregisterBucketResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeregisterBucketResponse) ProtoMessage() {}
+
+func (x *DeregisterBucketResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeregisterBucketResponse.ProtoReflect.Descriptor instead.
+func (*DeregisterBucketResponse) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{17}
+}
+
+type BucketInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Healthy bool   `protobuf:"varint,2,opt,name=healthy,proto3" json:"healthy,omitempty"` // connection to the bucket server is not failed
+	State   string `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`      // gRPC connectivity state
+}
+
+func (x *BucketInfo) Reset() {
+	*x = BucketInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BucketInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BucketInfo) ProtoMessage() {}
+
+func (x *BucketInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BucketInfo.ProtoReflect.Descriptor instead.
+func (*BucketInfo) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *BucketInfo) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *BucketInfo) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
+func (x *BucketInfo) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+type BucketList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Buckets []*BucketInfo `protobuf:"bytes,1,rep,name=buckets,proto3" json:"buckets,omitempty"`
+}
+
+func (x *BucketList) Reset() {
+	*x = BucketList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BucketList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BucketList) ProtoMessage() {}
+
+func (x *BucketList) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BucketList.ProtoReflect.Descriptor instead.
+func (*BucketList) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *BucketList) GetBuckets() []*BucketInfo {
+	if x != nil {
+		return x.Buckets
+	}
+	return nil
+}
+
+type Chunk struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *Chunk) Reset() {
+	*x = Chunk{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Chunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Chunk) ProtoMessage() {}
+
+func (x *Chunk) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Chunk.ProtoReflect.Descriptor instead.
+func (*Chunk) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Chunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type UploadChunk struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Fragment uint32 `protobuf:"varint,2,opt,name=fragment,proto3" json:"fragment,omitempty"`
+	Chunk    *Chunk `protobuf:"bytes,3,opt,name=chunk,proto3" json:"chunk,omitempty"`
+}
+
+func (x *UploadChunk) Reset() {
+	*x = UploadChunk{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UploadChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadChunk) ProtoMessage() {}
+
+func (x *UploadChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadChunk.ProtoReflect.Descriptor instead.
+func (*UploadChunk) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *UploadChunk) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *UploadChunk) GetFragment() uint32 {
+	if x != nil {
+		return x.Fragment
+	}
+	return 0
+}
+
+func (x *UploadChunk) GetChunk() *Chunk {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+type UploadResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Size     int64  `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`        // bytes persisted by the bucket server
+	Checksum string `protobuf:"bytes,2,opt,name=checksum,proto3" json:"checksum,omitempty"` // hex encoded SHA-256 of persisted bytes
+}
+
+func (x *UploadResponse) Reset() {
+	*x = UploadResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UploadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadResponse) ProtoMessage() {}
+
+func (x *UploadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadResponse.ProtoReflect.Descriptor instead.
+func (*UploadResponse) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *UploadResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *UploadResponse) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+type DownloadRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Fragment uint32 `protobuf:"varint,2,opt,name=fragment,proto3" json:"fragment,omitempty"`
+	Offset   int64  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"` // offset within the fragment
+	Length   int64  `protobuf:"varint,4,opt,name=length,proto3" json:"length,omitempty"` // 0 means up to the end of the fragment
+}
+
+func (x *DownloadRequest) Reset() {
+	*x = DownloadRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DownloadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadRequest) ProtoMessage() {}
+
+func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadRequest.ProtoReflect.Descriptor instead.
+func (*DownloadRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *DownloadRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *DownloadRequest) GetFragment() uint32 {
+	if x != nil {
+		return x.Fragment
+	}
+	return 0
+}
+
+func (x *DownloadRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *DownloadRequest) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+type DeleteFragmentRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Fragment uint32 `protobuf:"varint,2,opt,name=fragment,proto3" json:"fragment,omitempty"`
+}
+
+func (x *DeleteFragmentRequest) Reset() {
+	*x = DeleteFragmentRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteFragmentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFragmentRequest) ProtoMessage() {}
+
+func (x *DeleteFragmentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFragmentRequest.ProtoReflect.Descriptor instead.
+func (*DeleteFragmentRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *DeleteFragmentRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *DeleteFragmentRequest) GetFragment() uint32 {
+	if x != nil {
+		return x.Fragment
+	}
+	return 0
+}
+
+type DeleteFragmentResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *DeleteFragmentResponse) Reset() {
+	*x = DeleteFragmentResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteFragmentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFragmentResponse) ProtoMessage() {}
+
+func (x *DeleteFragmentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFragmentResponse.ProtoReflect.Descriptor instead.
+func (*DeleteFragmentResponse) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{25}
+}
+
+type StatFragmentRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Filename string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Fragment uint32 `protobuf:"varint,2,opt,name=fragment,proto3" json:"fragment,omitempty"`
+}
+
+func (x *StatFragmentRequest) Reset() {
+	*x = StatFragmentRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatFragmentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatFragmentRequest) ProtoMessage() {}
+
+func (x *StatFragmentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatFragmentRequest.ProtoReflect.Descriptor instead.
+func (*StatFragmentRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *StatFragmentRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *StatFragmentRequest) GetFragment() uint32 {
+	if x != nil {
+		return x.Fragment
+	}
+	return 0
+}
+
+type StatFragmentResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Size int64 `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"` // bytes stored by the bucket server
+}
+
+func (x *StatFragmentResponse) Reset() {
+	*x = StatFragmentResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_proto_bucket_proto_msgTypes[27]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatFragmentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatFragmentResponse) ProtoMessage() {}
+
+func (x *StatFragmentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_bucket_proto_msgTypes[27]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatFragmentResponse.ProtoReflect.Descriptor instead.
+func (*StatFragmentResponse) Descriptor() ([]byte, []int) {
+	return file_internal_proto_bucket_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *StatFragmentResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+var File_internal_proto_bucket_proto protoreflect.FileDescriptor
 
 var file_internal_proto_bucket_proto_rawDesc = []byte{
 	0x0a, 0x1b, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 	0x2f, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x62,
-	0x75, 0x63, 0x6b, 0x65, 0x74, 0x22, 0x20, 0x0a, 0x0a, 0x57, 0x73, 0x46, 0x69, 0x6c, 0x65, 0x49,
-	0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x22, 0x31, 0x0a, 0x15, 0x52, 0x65, 0x67, 0x69, 0x73,
-	0x74, 0x65, 0x72, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0x18, 0x0a, 0x16, 0x52, 0x65,
-	0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x1b, 0x0a, 0x05, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x12, 0x0a,
-	0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74,
-	0x61, 0x22, 0x6a, 0x0a, 0x0b, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x68, 0x75, 0x6e, 0x6b,
+	0x75, 0x63, 0x6b, 0x65, 0x74, 0x22, 0xfe, 0x02, 0x0a, 0x0a, 0x57, 0x73, 0x46, 0x69, 0x6c, 0x65,
+	0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x75,
+	0x6d, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x72, 0x65, 0x73,
+	0x75, 0x6d, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64,
+	0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x70, 0x6c, 0x6f, 0x61,
+	0x64, 0x49, 0x64, 0x12, 0x33, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x19, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x57, 0x73,
+	0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52,
+	0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x65, 0x78, 0x63, 0x6c,
+	0x75, 0x73, 0x69, 0x76, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x65, 0x78, 0x63,
+	0x6c, 0x75, 0x73, 0x69, 0x76, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73,
+	0x75, 0x6d, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73,
+	0x75, 0x6d, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6e, 0x74,
+	0x65, 0x6e, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b,
+	0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x3c, 0x0a, 0x08, 0x6d,
+	0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e,
+	0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x57, 0x73, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66,
+	0x6f, 0x2e, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x1a, 0x3b, 0x0a, 0x0d, 0x4d, 0x65, 0x74,
+	0x61, 0x64, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x64, 0x0a, 0x07, 0x57, 0x73, 0x45, 0x72, 0x72, 0x6f,
+	0x72, 0x12, 0x27, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x13, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x57, 0x73, 0x45, 0x72, 0x72, 0x6f, 0x72,
+	0x43, 0x6f, 0x64, 0x65, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65,
+	0x61, 0x73, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73,
+	0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x93, 0x01, 0x0a,
+	0x0f, 0x57, 0x73, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x12, 0x1b, 0x0a, 0x09, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x49, 0x64, 0x12, 0x16, 0x0a,
+	0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x6f,
+	0x66, 0x66, 0x73, 0x65, 0x74, 0x12, 0x33, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x19, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e,
+	0x57, 0x73, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x77, 0x69,
+	0x6e, 0x64, 0x6f, 0x77, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x77, 0x69, 0x6e, 0x64,
+	0x6f, 0x77, 0x22, 0x40, 0x0a, 0x05, 0x57, 0x73, 0x41, 0x63, 0x6b, 0x12, 0x19, 0x0a, 0x08, 0x6e,
+	0x65, 0x78, 0x74, 0x5f, 0x73, 0x65, 0x71, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x6e,
+	0x65, 0x78, 0x74, 0x53, 0x65, 0x71, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74,
+	0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x69,
+	0x74, 0x74, 0x65, 0x64, 0x22, 0x6e, 0x0a, 0x0e, 0x57, 0x73, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x12,
+	0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69,
+	0x7a, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x12, 0x14,
+	0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x22, 0x89, 0x01, 0x0a, 0x0f, 0x57, 0x73, 0x53, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1f, 0x0a, 0x03, 0x61, 0x63, 0x6b, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x57,
+	0x73, 0x41, 0x63, 0x6b, 0x52, 0x03, 0x61, 0x63, 0x6b, 0x12, 0x2e, 0x0a, 0x06, 0x73, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x62, 0x75, 0x63, 0x6b,
+	0x65, 0x74, 0x2e, 0x57, 0x73, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x25, 0x0a, 0x05, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65,
+	0x74, 0x2e, 0x57, 0x73, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x22, 0x63, 0x0a, 0x15, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x44, 0x65, 0x6c, 0x65,
+	0x74, 0x65, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x72, 0x61,
+	0x67, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x66, 0x72, 0x61,
+	0x67, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12,
+	0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0xbd, 0x01, 0x0a, 0x10, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x46, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69,
+	0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69,
+	0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65,
+	0x6e, 0x74, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x66, 0x72, 0x61, 0x67, 0x6d,
+	0x65, 0x6e, 0x74, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x12, 0x1a,
+	0x0a, 0x08, 0x64, 0x65, 0x66, 0x65, 0x72, 0x72, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x08, 0x64, 0x65, 0x66, 0x65, 0x72, 0x72, 0x65, 0x64, 0x12, 0x39, 0x0a, 0x08, 0x66, 0x61,
+	0x69, 0x6c, 0x75, 0x72, 0x65, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x62,
+	0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x44, 0x65,
+	0x6c, 0x65, 0x74, 0x65, 0x46, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x52, 0x08, 0x66, 0x61, 0x69,
+	0x6c, 0x75, 0x72, 0x65, 0x73, 0x22, 0xa8, 0x01, 0x0a, 0x0c, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65,
+	0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65,
+	0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65,
+	0x6e, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x12, 0x0a, 0x04,
+	0x73, 0x69, 0x7a, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65,
+	0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x12, 0x16, 0x0a, 0x06,
+	0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x6f, 0x66,
+	0x66, 0x73, 0x65, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x73,
+	0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x73,
+	0x22, 0xf0, 0x03, 0x0a, 0x08, 0x46, 0x69, 0x6c, 0x65, 0x53, 0x74, 0x61, 0x74, 0x12, 0x1a, 0x0a,
+	0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x16, 0x0a,
+	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e,
+	0x74, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65,
+	0x6e, 0x74, 0x73, 0x12, 0x32, 0x0a, 0x09, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x6e, 0x74,
+	0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e,
+	0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x09, 0x70, 0x6c,
+	0x61, 0x63, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b,
+	0x73, 0x75, 0x6d, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b,
+	0x73, 0x75, 0x6d, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x61,
+	0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64,
+	0x41, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x5f,
+	0x61, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65,
+	0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x18, 0x09,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x12, 0x1c, 0x0a, 0x09, 0x65,
+	0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09,
+	0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6e,
+	0x74, 0x65, 0x6e, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x3a, 0x0a, 0x08,
+	0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x18, 0x0c, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e,
+	0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x53, 0x74, 0x61, 0x74,
+	0x2e, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08,
+	0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x1c, 0x0a, 0x09, 0x6e, 0x61, 0x6d, 0x65,
+	0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6e, 0x61, 0x6d,
+	0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x1a, 0x3b, 0x0a, 0x0d, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61,
+	0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
+	0x02, 0x38, 0x01, 0x22, 0x53, 0x0a, 0x08, 0x46, 0x69, 0x6c, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x12,
+	0x26, 0x0a, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10,
+	0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x53, 0x74, 0x61, 0x74,
+	0x52, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6e, 0x65, 0x78, 0x74, 0x5f,
+	0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6e, 0x65,
+	0x78, 0x74, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x22, 0xc2, 0x01, 0x0a, 0x0d, 0x4e, 0x61, 0x6d,
+	0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x20,
+	0x0a, 0x0b, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x0b, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x14, 0x0a, 0x05, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x05, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x74, 0x65, 0x6e, 0x74,
+	0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x74, 0x65, 0x6e,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f,
+	0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65,
+	0x64, 0x41, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x22, 0x46, 0x0a,
+	0x0d, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x35,
+	0x0a, 0x0a, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x15, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x4e, 0x61, 0x6d, 0x65,
+	0x73, 0x70, 0x61, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x0a, 0x6e, 0x61, 0x6d, 0x65, 0x73,
+	0x70, 0x61, 0x63, 0x65, 0x73, 0x22, 0x48, 0x0a, 0x11, 0x4b, 0x65, 0x79, 0x52, 0x6f, 0x74, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x15, 0x0a, 0x06, 0x6b, 0x65,
+	0x79, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6b, 0x65, 0x79, 0x49,
+	0x64, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x77, 0x72, 0x61, 0x70, 0x70, 0x65, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x72, 0x65, 0x77, 0x72, 0x61, 0x70, 0x70, 0x65, 0x64, 0x22,
+	0x31, 0x0a, 0x15, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x42, 0x75, 0x63, 0x6b, 0x65,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65,
+	0x73, 0x73, 0x22, 0x18, 0x0a, 0x16, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x42, 0x75,
+	0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x33, 0x0a, 0x17,
+	0x44, 0x65, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65,
+	0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73,
+	0x73, 0x22, 0x1a, 0x0a, 0x18, 0x44, 0x65, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x42,
+	0x75, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x56, 0x0a,
+	0x0a, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x18, 0x0a, 0x07, 0x61,
+	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64,
+	0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x79,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x79, 0x12,
+	0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x22, 0x3a, 0x0a, 0x0a, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x4c,
+	0x69, 0x73, 0x74, 0x12, 0x2c, 0x0a, 0x07, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x42, 0x75,
+	0x63, 0x6b, 0x65, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74,
+	0x73, 0x22, 0x1b, 0x0a, 0x05, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61,
+	0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x6a,
+	0x0a, 0x0b, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x1a, 0x0a,
+	0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x72, 0x61,
+	0x67, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x66, 0x72, 0x61,
+	0x67, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x23, 0x0a, 0x05, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x43, 0x68,
+	0x75, 0x6e, 0x6b, 0x52, 0x05, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x22, 0x40, 0x0a, 0x0e, 0x55, 0x70,
+	0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04,
+	0x73, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65,
+	0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x22, 0x79, 0x0a, 0x0f,
+	0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x66,
+	0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x66,
+	0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x12,
+	0x16, 0x0a, 0x06, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x06, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x22, 0x4f, 0x0a, 0x15, 0x44, 0x65, 0x6c, 0x65, 0x74,
+	0x65, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
 	0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
 	0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08,
 	0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08,
-	0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x23, 0x0a, 0x05, 0x63, 0x68, 0x75, 0x6e,
-	0x6b, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74,
-	0x2e, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x52, 0x05, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x22, 0x10, 0x0a,
-	0x0e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
-	0x49, 0x0a, 0x0f, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a,
-	0x0a, 0x08, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d,
-	0x52, 0x08, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x22, 0x4f, 0x0a, 0x15, 0x44, 0x65,
-	0x6c, 0x65, 0x74, 0x65, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12,
-	0x1a, 0x0a, 0x08, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x0d, 0x52, 0x08, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x22, 0x18, 0x0a, 0x16, 0x44,
-	0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0x5f, 0x0a, 0x0a, 0x41, 0x70, 0x69, 0x53, 0x65, 0x72, 0x76,
-	0x69, 0x63, 0x65, 0x12, 0x51, 0x0a, 0x0e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x42,
-	0x75, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x1d, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x52,
-	0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x52, 0x65,
-	0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x32, 0xe1, 0x01, 0x0a, 0x0d, 0x42, 0x75, 0x63, 0x6b, 0x65,
-	0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x3f, 0x0a, 0x0c, 0x55, 0x70, 0x6c, 0x6f,
-	0x61, 0x64, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x73, 0x12, 0x13, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65,
-	0x74, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x1a, 0x16, 0x2e,
-	0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x28, 0x01, 0x12, 0x3c, 0x0a, 0x0e, 0x44, 0x6f, 0x77,
-	0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x73, 0x12, 0x17, 0x2e, 0x62, 0x75,
-	0x63, 0x6b, 0x65, 0x74, 0x2e, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x0d, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x43, 0x68,
-	0x75, 0x6e, 0x6b, 0x22, 0x00, 0x30, 0x01, 0x12, 0x51, 0x0a, 0x0e, 0x44, 0x65, 0x6c, 0x65, 0x74,
-	0x65, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x1d, 0x2e, 0x62, 0x75, 0x63, 0x6b,
-	0x65, 0x74, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e,
-	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65,
-	0x74, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2f,
-	0x3b, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x22, 0x18, 0x0a, 0x16, 0x44, 0x65, 0x6c, 0x65,
+	0x74, 0x65, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x4d, 0x0a, 0x13, 0x53, 0x74, 0x61, 0x74, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65,
+	0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c,
+	0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c,
+	0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e,
+	0x74, 0x22, 0x2a, 0x0a, 0x14, 0x53, 0x74, 0x61, 0x74, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e,
+	0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x2a, 0x42, 0x0a,
+	0x11, 0x57, 0x73, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x15, 0x0a, 0x11, 0x57, 0x53, 0x5f, 0x50, 0x52, 0x4f, 0x54, 0x4f, 0x43, 0x4f,
+	0x4c, 0x5f, 0x50, 0x4c, 0x41, 0x49, 0x4e, 0x10, 0x00, 0x12, 0x16, 0x0a, 0x12, 0x57, 0x53, 0x5f,
+	0x50, 0x52, 0x4f, 0x54, 0x4f, 0x43, 0x4f, 0x4c, 0x5f, 0x46, 0x52, 0x41, 0x4d, 0x45, 0x44, 0x10,
+	0x01, 0x2a, 0xce, 0x02, 0x0a, 0x0b, 0x57, 0x73, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x43, 0x6f, 0x64,
+	0x65, 0x12, 0x11, 0x0a, 0x0d, 0x57, 0x53, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x5f, 0x4e, 0x4f,
+	0x4e, 0x45, 0x10, 0x00, 0x12, 0x15, 0x0a, 0x11, 0x57, 0x53, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52,
+	0x5f, 0x49, 0x4e, 0x54, 0x45, 0x52, 0x4e, 0x41, 0x4c, 0x10, 0x01, 0x12, 0x18, 0x0a, 0x14, 0x57,
+	0x53, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x5f, 0x42, 0x41, 0x44, 0x5f, 0x52, 0x45, 0x51, 0x55,
+	0x45, 0x53, 0x54, 0x10, 0x02, 0x12, 0x16, 0x0a, 0x12, 0x57, 0x53, 0x5f, 0x45, 0x52, 0x52, 0x4f,
+	0x52, 0x5f, 0x4e, 0x4f, 0x54, 0x5f, 0x46, 0x4f, 0x55, 0x4e, 0x44, 0x10, 0x03, 0x12, 0x18, 0x0a,
+	0x14, 0x57, 0x53, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x5f, 0x4e, 0x4f, 0x5f, 0x43, 0x41, 0x50,
+	0x41, 0x43, 0x49, 0x54, 0x59, 0x10, 0x04, 0x12, 0x18, 0x0a, 0x14, 0x57, 0x53, 0x5f, 0x45, 0x52,
+	0x52, 0x4f, 0x52, 0x5f, 0x46, 0x49, 0x4c, 0x45, 0x5f, 0x45, 0x58, 0x49, 0x53, 0x54, 0x53, 0x10,
+	0x05, 0x12, 0x1e, 0x0a, 0x1a, 0x57, 0x53, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x5f, 0x43, 0x48,
+	0x45, 0x43, 0x4b, 0x53, 0x55, 0x4d, 0x5f, 0x4d, 0x49, 0x53, 0x4d, 0x41, 0x54, 0x43, 0x48, 0x10,
+	0x06, 0x12, 0x1b, 0x0a, 0x17, 0x57, 0x53, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x5f, 0x51, 0x55,
+	0x4f, 0x54, 0x41, 0x5f, 0x45, 0x58, 0x43, 0x45, 0x45, 0x44, 0x45, 0x44, 0x10, 0x07, 0x12, 0x1a,
+	0x0a, 0x16, 0x57, 0x53, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x5f, 0x55, 0x50, 0x4c, 0x4f, 0x41,
+	0x44, 0x5f, 0x41, 0x43, 0x54, 0x49, 0x56, 0x45, 0x10, 0x08, 0x12, 0x20, 0x0a, 0x1c, 0x57, 0x53,
+	0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x5f, 0x4e, 0x41, 0x4d, 0x45, 0x53, 0x50, 0x41, 0x43, 0x45,
+	0x5f, 0x4e, 0x4f, 0x54, 0x5f, 0x45, 0x4d, 0x50, 0x54, 0x59, 0x10, 0x09, 0x12, 0x1c, 0x0a, 0x18,
+	0x57, 0x53, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x5f, 0x55, 0x4e, 0x41, 0x55, 0x54, 0x48, 0x45,
+	0x4e, 0x54, 0x49, 0x43, 0x41, 0x54, 0x45, 0x44, 0x10, 0x0a, 0x12, 0x16, 0x0a, 0x12, 0x57, 0x53,
+	0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x5f, 0x46, 0x4f, 0x52, 0x42, 0x49, 0x44, 0x44, 0x45, 0x4e,
+	0x10, 0x0b, 0x32, 0xb8, 0x01, 0x0a, 0x0a, 0x41, 0x70, 0x69, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x12, 0x51, 0x0a, 0x0e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x42, 0x75, 0x63,
+	0x6b, 0x65, 0x74, 0x12, 0x1d, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x52, 0x65, 0x67,
+	0x69, 0x73, 0x74, 0x65, 0x72, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x52, 0x65, 0x67, 0x69,
+	0x73, 0x74, 0x65, 0x72, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x00, 0x12, 0x57, 0x0a, 0x10, 0x44, 0x65, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74,
+	0x65, 0x72, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x1f, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65,
+	0x74, 0x2e, 0x44, 0x65, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x42, 0x75, 0x63, 0x6b,
+	0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x62, 0x75, 0x63, 0x6b,
+	0x65, 0x74, 0x2e, 0x44, 0x65, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x42, 0x75, 0x63,
+	0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x32, 0xae, 0x02,
+	0x0a, 0x0d, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12,
+	0x3f, 0x0a, 0x0c, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x73, 0x12,
+	0x13, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x43,
+	0x68, 0x75, 0x6e, 0x6b, 0x1a, 0x16, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x55, 0x70,
+	0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x28, 0x01,
+	0x12, 0x3c, 0x0a, 0x0e, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x68, 0x75, 0x6e,
+	0x6b, 0x73, 0x12, 0x17, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x44, 0x6f, 0x77, 0x6e,
+	0x6c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0d, 0x2e, 0x62, 0x75,
+	0x63, 0x6b, 0x65, 0x74, 0x2e, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x22, 0x00, 0x30, 0x01, 0x12, 0x51,
+	0x0a, 0x0e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74,
+	0x12, 0x1d, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1e, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46,
+	0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0x00, 0x12, 0x4b, 0x0a, 0x0c, 0x53, 0x74, 0x61, 0x74, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e,
+	0x74, 0x12, 0x1b, 0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x46,
+	0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c,
+	0x2e, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x46, 0x72, 0x61, 0x67,
+	0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x0b,
+	0x5a, 0x09, 0x2e, 0x2f, 0x3b, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -517,33 +2190,74 @@ func file_internal_proto_bucket_proto_rawDescGZIP() []byte {
 	return file_internal_proto_bucket_proto_rawDescData
 }
 
-var file_internal_proto_bucket_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_internal_proto_bucket_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_internal_proto_bucket_proto_msgTypes = make([]protoimpl.MessageInfo, 30)
 var file_internal_proto_bucket_proto_goTypes = []interface{}{
-	(*WsFileInfo)(nil),             // 0: bucket.WsFileInfo
-	(*RegisterBucketRequest)(nil),  // 1: bucket.RegisterBucketRequest
-	(*RegisterBucketResponse)(nil), // 2: bucket.RegisterBucketResponse
-	(*Chunk)(nil),                  // 3: bucket.Chunk
-	(*UploadChunk)(nil),            // 4: bucket.UploadChunk
-	(*UploadResponse)(nil),         // 5: bucket.UploadResponse
-	(*DownloadRequest)(nil),        // 6: bucket.DownloadRequest
-	(*DeleteFragmentRequest)(nil),  // 7: bucket.DeleteFragmentRequest
-	(*DeleteFragmentResponse)(nil), // 8: bucket.DeleteFragmentResponse
+	(WsProtocolVersion)(0),           // 0: bucket.WsProtocolVersion
+	(WsErrorCode)(0),                 // 1: bucket.WsErrorCode
+	(*WsFileInfo)(nil),               // 2: bucket.WsFileInfo
+	(*WsError)(nil),                  // 3: bucket.WsError
+	(*WsUploadSession)(nil),          // 4: bucket.WsUploadSession
+	(*WsAck)(nil),                    // 5: bucket.WsAck
+	(*WsUploadStatus)(nil),           // 6: bucket.WsUploadStatus
+	(*WsServerMessage)(nil),          // 7: bucket.WsServerMessage
+	(*FragmentDeleteFailure)(nil),    // 8: bucket.FragmentDeleteFailure
+	(*DeleteFileReport)(nil),         // 9: bucket.DeleteFileReport
+	(*FragmentInfo)(nil),             // 10: bucket.FragmentInfo
+	(*FileStat)(nil),                 // 11: bucket.FileStat
+	(*FileList)(nil),                 // 12: bucket.FileList
+	(*NamespaceInfo)(nil),            // 13: bucket.NamespaceInfo
+	(*NamespaceList)(nil),            // 14: bucket.NamespaceList
+	(*KeyRotationReport)(nil),        // 15: bucket.KeyRotationReport
+	(*RegisterBucketRequest)(nil),    // 16: bucket.RegisterBucketRequest
+	(*RegisterBucketResponse)(nil),   // 17: bucket.RegisterBucketResponse
+	(*DeregisterBucketRequest)(nil),  // 18: bucket.DeregisterBucketRequest
+	(*DeregisterBucketResponse)(nil), // 19: bucket.DeregisterBucketResponse
+	(*BucketInfo)(nil),               // 20: bucket.BucketInfo
+	(*BucketList)(nil),               // 21: bucket.BucketList
+	(*Chunk)(nil),                    // 22: bucket.Chunk
+	(*UploadChunk)(nil),              // 23: bucket.UploadChunk
+	(*UploadResponse)(nil),           // 24: bucket.UploadResponse
+	(*DownloadRequest)(nil),          // 25: bucket.DownloadRequest
+	(*DeleteFragmentRequest)(nil),    // 26: bucket.DeleteFragmentRequest
+	(*DeleteFragmentResponse)(nil),   // 27: bucket.DeleteFragmentResponse
+	(*StatFragmentRequest)(nil),      // 28: bucket.StatFragmentRequest
+	(*StatFragmentResponse)(nil),     // 29: bucket.StatFragmentResponse
+	nil,                              // 30: bucket.WsFileInfo.MetadataEntry
+	nil,                              // 31: bucket.FileStat.MetadataEntry
 }
 var file_internal_proto_bucket_proto_depIdxs = []int32{
-	3, // 0: bucket.UploadChunk.chunk:type_name -> bucket.Chunk
-	1, // 1: bucket.ApiService.RegisterBucket:input_type -> bucket.RegisterBucketRequest
-	4, // 2: bucket.BucketService.UploadChunks:input_type -> bucket.UploadChunk
-	6, // 3: bucket.BucketService.DownloadChunks:input_type -> bucket.DownloadRequest
-	7, // 4: bucket.BucketService.DeleteFragment:input_type -> bucket.DeleteFragmentRequest
-	2, // 5: bucket.ApiService.RegisterBucket:output_type -> bucket.RegisterBucketResponse
-	5, // 6: bucket.BucketService.UploadChunks:output_type -> bucket.UploadResponse
-	3, // 7: bucket.BucketService.DownloadChunks:output_type -> bucket.Chunk
-	8, // 8: bucket.BucketService.DeleteFragment:output_type -> bucket.DeleteFragmentResponse
-	5, // [5:9] is the sub-list for method output_type
-	1, // [1:5] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: bucket.WsFileInfo.version:type_name -> bucket.WsProtocolVersion
+	30, // 1: bucket.WsFileInfo.metadata:type_name -> bucket.WsFileInfo.MetadataEntry
+	1,  // 2: bucket.WsError.code:type_name -> bucket.WsErrorCode
+	0,  // 3: bucket.WsUploadSession.version:type_name -> bucket.WsProtocolVersion
+	5,  // 4: bucket.WsServerMessage.ack:type_name -> bucket.WsAck
+	6,  // 5: bucket.WsServerMessage.status:type_name -> bucket.WsUploadStatus
+	3,  // 6: bucket.WsServerMessage.error:type_name -> bucket.WsError
+	8,  // 7: bucket.DeleteFileReport.failures:type_name -> bucket.FragmentDeleteFailure
+	10, // 8: bucket.FileStat.placement:type_name -> bucket.FragmentInfo
+	31, // 9: bucket.FileStat.metadata:type_name -> bucket.FileStat.MetadataEntry
+	11, // 10: bucket.FileList.files:type_name -> bucket.FileStat
+	13, // 11: bucket.NamespaceList.namespaces:type_name -> bucket.NamespaceInfo
+	20, // 12: bucket.BucketList.buckets:type_name -> bucket.BucketInfo
+	22, // 13: bucket.UploadChunk.chunk:type_name -> bucket.Chunk
+	16, // 14: bucket.ApiService.RegisterBucket:input_type -> bucket.RegisterBucketRequest
+	18, // 15: bucket.ApiService.DeregisterBucket:input_type -> bucket.DeregisterBucketRequest
+	23, // 16: bucket.BucketService.UploadChunks:input_type -> bucket.UploadChunk
+	25, // 17: bucket.BucketService.DownloadChunks:input_type -> bucket.DownloadRequest
+	26, // 18: bucket.BucketService.DeleteFragment:input_type -> bucket.DeleteFragmentRequest
+	28, // 19: bucket.BucketService.StatFragment:input_type -> bucket.StatFragmentRequest
+	17, // 20: bucket.ApiService.RegisterBucket:output_type -> bucket.RegisterBucketResponse
+	19, // 21: bucket.ApiService.DeregisterBucket:output_type -> bucket.DeregisterBucketResponse
+	24, // 22: bucket.BucketService.UploadChunks:output_type -> bucket.UploadResponse
+	22, // 23: bucket.BucketService.DownloadChunks:output_type -> bucket.Chunk
+	27, // 24: bucket.BucketService.DeleteFragment:output_type -> bucket.DeleteFragmentResponse
+	29, // 25: bucket.BucketService.StatFragment:output_type -> bucket.StatFragmentResponse
+	20, // [20:26] is the sub-list for method output_type
+	14, // [14:20] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
 }
 
 func init() { file_internal_proto_bucket_proto_init() }
@@ -565,7 +2279,7 @@ func file_internal_proto_bucket_proto_init() {
 			}
 		}
 		file_internal_proto_bucket_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RegisterBucketRequest); i {
+			switch v := v.(*WsError); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -577,7 +2291,7 @@ func file_internal_proto_bucket_proto_init() {
 			}
 		}
 		file_internal_proto_bucket_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RegisterBucketResponse); i {
+			switch v := v.(*WsUploadSession); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -589,7 +2303,7 @@ func file_internal_proto_bucket_proto_init() {
 			}
 		}
 		file_internal_proto_bucket_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Chunk); i {
+			switch v := v.(*WsAck); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -601,7 +2315,7 @@ func file_internal_proto_bucket_proto_init() {
 			}
 		}
 		file_internal_proto_bucket_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*UploadChunk); i {
+			switch v := v.(*WsUploadStatus); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -613,7 +2327,7 @@ func file_internal_proto_bucket_proto_init() {
 			}
 		}
 		file_internal_proto_bucket_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*UploadResponse); i {
+			switch v := v.(*WsServerMessage); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -625,7 +2339,7 @@ func file_internal_proto_bucket_proto_init() {
 			}
 		}
 		file_internal_proto_bucket_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DownloadRequest); i {
+			switch v := v.(*FragmentDeleteFailure); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -637,7 +2351,7 @@ func file_internal_proto_bucket_proto_init() {
 			}
 		}
 		file_internal_proto_bucket_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DeleteFragmentRequest); i {
+			switch v := v.(*DeleteFileReport); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -649,6 +2363,210 @@ func file_internal_proto_bucket_proto_init() {
 			}
 		}
 		file_internal_proto_bucket_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FragmentInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileStat); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NamespaceInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NamespaceList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*KeyRotationReport); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RegisterBucketRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RegisterBucketResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeregisterBucketRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeregisterBucketResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BucketInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BucketList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Chunk); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UploadChunk); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UploadResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DownloadRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteFragmentRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*DeleteFragmentResponse); i {
 			case 0:
 				return &v.state
@@ -660,19 +2578,44 @@ func file_internal_proto_bucket_proto_init() {
 				return nil
 			}
 		}
+		file_internal_proto_bucket_proto_msgTypes[26].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatFragmentRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_proto_bucket_proto_msgTypes[27].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatFragmentResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_internal_proto_bucket_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   9,
+			NumEnums:      2,
+			NumMessages:   30,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
 		GoTypes:           file_internal_proto_bucket_proto_goTypes,
 		DependencyIndexes: file_internal_proto_bucket_proto_depIdxs,
+		EnumInfos:         file_internal_proto_bucket_proto_enumTypes,
 		MessageInfos:      file_internal_proto_bucket_proto_msgTypes,
 	}.Build()
 	File_internal_proto_bucket_proto = out.File
@@ -23,6 +23,7 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ApiServiceClient interface {
 	RegisterBucket(ctx context.Context, in *RegisterBucketRequest, opts ...grpc.CallOption) (*RegisterBucketResponse, error)
+	DeregisterBucket(ctx context.Context, in *DeregisterBucketRequest, opts ...grpc.CallOption) (*DeregisterBucketResponse, error)
 }
 
 type apiServiceClient struct {
@@ -42,11 +43,21 @@ func (c *apiServiceClient) RegisterBucket(ctx context.Context, in *RegisterBucke
 	return out, nil
 }
 
+func (c *apiServiceClient) DeregisterBucket(ctx context.Context, in *DeregisterBucketRequest, opts ...grpc.CallOption) (*DeregisterBucketResponse, error) {
+	out := new(DeregisterBucketResponse)
+	err := c.cc.Invoke(ctx, "/bucket.ApiService/DeregisterBucket", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ApiServiceServer is the server API for ApiService service.
 // All implementations must embed UnimplementedApiServiceServer
 // for forward compatibility
 type ApiServiceServer interface {
 	RegisterBucket(context.Context, *RegisterBucketRequest) (*RegisterBucketResponse, error)
+	DeregisterBucket(context.Context, *DeregisterBucketRequest) (*DeregisterBucketResponse, error)
 	mustEmbedUnimplementedApiServiceServer()
 }
 
@@ -57,6 +68,9 @@ type UnimplementedApiServiceServer struct {
 func (UnimplementedApiServiceServer) RegisterBucket(context.Context, *RegisterBucketRequest) (*RegisterBucketResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RegisterBucket not implemented")
 }
+func (UnimplementedApiServiceServer) DeregisterBucket(context.Context, *DeregisterBucketRequest) (*DeregisterBucketResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeregisterBucket not implemented")
+}
 func (UnimplementedApiServiceServer) mustEmbedUnimplementedApiServiceServer() {}
 
 // UnsafeApiServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +102,24 @@ func _ApiService_RegisterBucket_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ApiService_DeregisterBucket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeregisterBucketRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServiceServer).DeregisterBucket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/bucket.ApiService/DeregisterBucket",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServiceServer).DeregisterBucket(ctx, req.(*DeregisterBucketRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ApiService_ServiceDesc is the grpc.ServiceDesc for ApiService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,6 +131,10 @@ var ApiService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RegisterBucket",
 			Handler:    _ApiService_RegisterBucket_Handler,
 		},
+		{
+			MethodName: "DeregisterBucket",
+			Handler:    _ApiService_DeregisterBucket_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/proto/bucket.proto",
@@ -111,6 +147,7 @@ type BucketServiceClient interface {
 	UploadChunks(ctx context.Context, opts ...grpc.CallOption) (BucketService_UploadChunksClient, error)
 	DownloadChunks(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (BucketService_DownloadChunksClient, error)
 	DeleteFragment(ctx context.Context, in *DeleteFragmentRequest, opts ...grpc.CallOption) (*DeleteFragmentResponse, error)
+	StatFragment(ctx context.Context, in *StatFragmentRequest, opts ...grpc.CallOption) (*StatFragmentResponse, error)
 }
 
 type bucketServiceClient struct {
@@ -196,6 +233,15 @@ func (c *bucketServiceClient) DeleteFragment(ctx context.Context, in *DeleteFrag
 	return out, nil
 }
 
+func (c *bucketServiceClient) StatFragment(ctx context.Context, in *StatFragmentRequest, opts ...grpc.CallOption) (*StatFragmentResponse, error) {
+	out := new(StatFragmentResponse)
+	err := c.cc.Invoke(ctx, "/bucket.BucketService/StatFragment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BucketServiceServer is the server API for BucketService service.
 // All implementations must embed UnimplementedBucketServiceServer
 // for forward compatibility
@@ -203,6 +249,7 @@ type BucketServiceServer interface {
 	UploadChunks(BucketService_UploadChunksServer) error
 	DownloadChunks(*DownloadRequest, BucketService_DownloadChunksServer) error
 	DeleteFragment(context.Context, *DeleteFragmentRequest) (*DeleteFragmentResponse, error)
+	StatFragment(context.Context, *StatFragmentRequest) (*StatFragmentResponse, error)
 	mustEmbedUnimplementedBucketServiceServer()
 }
 
@@ -219,6 +266,9 @@ func (UnimplementedBucketServiceServer) DownloadChunks(*DownloadRequest, BucketS
 func (UnimplementedBucketServiceServer) DeleteFragment(context.Context, *DeleteFragmentRequest) (*DeleteFragmentResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteFragment not implemented")
 }
+func (UnimplementedBucketServiceServer) StatFragment(context.Context, *StatFragmentRequest) (*StatFragmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StatFragment not implemented")
+}
 func (UnimplementedBucketServiceServer) mustEmbedUnimplementedBucketServiceServer() {}
 
 // UnsafeBucketServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -297,6 +347,24 @@ func _BucketService_DeleteFragment_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BucketService_StatFragment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatFragmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BucketServiceServer).StatFragment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/bucket.BucketService/StatFragment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BucketServiceServer).StatFragment(ctx, req.(*StatFragmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BucketService_ServiceDesc is the grpc.ServiceDesc for BucketService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -308,6 +376,10 @@ var BucketService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteFragment",
 			Handler:    _BucketService_DeleteFragment_Handler,
 		},
+		{
+			MethodName: "StatFragment",
+			Handler:    _BucketService_StatFragment_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -13,6 +13,10 @@ const (
 type (
 	Server struct {
 		Address string
+
+		// connection health, reported by API server
+		Unhealthy bool
+		State     string
 	}
 
 	Registry struct {
@@ -35,8 +39,14 @@ func (r *Registry) Register(s *Server) error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	for _, server := range r.servers {
+	for i, server := range r.servers {
 		if server.Address == s.Address {
+			// server, which failed without deregistration, is registered again after restart
+			if server.Unhealthy {
+				r.servers[i] = s
+				return nil
+			}
+
 			return errors.New("server is already registered")
 		}
 	}
@@ -44,16 +54,96 @@ func (r *Registry) Register(s *Server) error {
 	return nil
 }
 
+func (r *Registry) Deregister(address string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, server := range r.servers {
+		if server.Address == address {
+			r.servers = append(r.servers[:i], r.servers[i+1:]...)
+			return nil
+		}
+	}
+
+	return errors.New("server is not registered")
+}
+
+// SetHealth records connection state of the server, unhealthy servers are skipped by placement.
+func (r *Registry) SetHealth(address string, healthy bool, state string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, server := range r.servers {
+		if server.Address == address {
+			server.Unhealthy = !healthy
+			server.State = state
+		}
+	}
+}
+
+func (r *Registry) Registered(address string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, server := range r.servers {
+		if server.Address == address {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Registry) Servers() []Server {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	servers := make([]Server, 0, len(r.servers))
+	for _, server := range r.servers {
+		servers = append(servers, *server)
+	}
+
+	return servers
+}
+
+func (r *Registry) healthy() []*Server {
+	servers := make([]*Server, 0, len(r.servers))
+	for _, server := range r.servers {
+		if !server.Unhealthy {
+			servers = append(servers, server)
+		}
+	}
+
+	return servers
+}
+
+func (r *Registry) Count() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return len(r.healthy())
+}
+
 func (r *Registry) GetServer(chunkHash hash.Hash64) *Server {
+	servers := r.Ring(chunkHash)
+	if len(servers) == 0 {
+		return nil
+	}
+
+	return servers[0]
+}
+
+// Ring returns healthy servers starting from the one chosen by the hash, the rest are failover candidates.
+func (r *Registry) Ring(chunkHash hash.Hash64) []*Server {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	if len(r.servers) == 0 {
+	servers := r.healthy()
+	if len(servers) == 0 {
 		return nil
 	}
 
-	idx := chunkHash.Sum64() % uint64(len(r.servers))
+	idx := chunkHash.Sum64() % uint64(len(servers))
 
-	return r.servers[idx]
+	return append(servers[idx:], servers[:idx]...)
 }
-
@@ -0,0 +1,33 @@
+package wsproto
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// FrameHeaderSize is a size of the chunk sequence number, which precedes chunk data in a framed binary message.
+	FrameHeaderSize = 8
+)
+
+var (
+	ErrInvalidFrame = errors.New("invalid chunk frame")
+)
+
+// EncodeFrame prepends data with its sequence number. Frame with empty data terminates the upload.
+func EncodeFrame(seq uint64, data []byte) []byte {
+	frame := make([]byte, FrameHeaderSize+len(data))
+	binary.BigEndian.PutUint64(frame, seq)
+	copy(frame[FrameHeaderSize:], data)
+
+	return frame
+}
+
+// DecodeFrame splits framed binary message to sequence number and data.
+func DecodeFrame(frame []byte) (uint64, []byte, error) {
+	if len(frame) < FrameHeaderSize {
+		return 0, nil, ErrInvalidFrame
+	}
+
+	return binary.BigEndian.Uint64(frame), frame[FrameHeaderSize:], nil
+}
@@ -0,0 +1,46 @@
+package wsproto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  uint64
+		data []byte
+	}{
+		{name: "first chunk", seq: 0, data: []byte("chunk")},
+		{name: "large sequence number", seq: 1<<64 - 1, data: []byte("chunk")},
+		{name: "terminating frame", seq: 10, data: []byte{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame := EncodeFrame(tt.seq, tt.data)
+			if len(frame) != FrameHeaderSize+len(tt.data) {
+				t.Errorf("frame size = %d, want %d", len(frame), FrameHeaderSize+len(tt.data))
+			}
+
+			seq, data, err := DecodeFrame(frame)
+			if err != nil {
+				t.Fatalf("DecodeFrame() error = %v", err)
+			}
+
+			if seq != tt.seq || !bytes.Equal(data, tt.data) {
+				t.Errorf("DecodeFrame() = %d, %q, want %d, %q", seq, data, tt.seq, tt.data)
+			}
+		})
+	}
+}
+
+func TestDecodeFrameInvalid(t *testing.T) {
+	for _, frame := range [][]byte{nil, {}, make([]byte, FrameHeaderSize-1)} {
+		_, _, err := DecodeFrame(frame)
+		if !errors.Is(err, ErrInvalidFrame) {
+			t.Errorf("DecodeFrame() of %d bytes error = %v, want %v", len(frame), err, ErrInvalidFrame)
+		}
+	}
+}